@@ -0,0 +1,106 @@
+package sb
+
+import "reflect"
+
+// UnmarshalAtomic is like Unmarshal, but leaves the target untouched if unmarshaling fails
+func UnmarshalAtomic(target any) Sink {
+	return UnmarshalValue(
+		DefaultCtx.Atomic(),
+		reflect.ValueOf(target),
+		nil,
+	)
+}
+
+func unmarshalAtomic(ctx Ctx, target reflect.Value, cont Sink) Sink {
+	ctx = ctx.withOptions(func(options *ctxOptions) {
+		options.AtomicUnmarshal = false
+	})
+	shadow := reflect.New(target.Type().Elem())
+	shadow.Elem().Set(cloneForUnmarshal(ctx, target.Elem()))
+	return ctx.Unmarshal(ctx, shadow, func(token *Token) (Sink, error) {
+		target.Elem().Set(shadow.Elem())
+		return cont.Sink(token)
+	})
+}
+
+// cloneForUnmarshal copies parts of value that unmarshaling may modify in place.
+// pointers are followed only in MergePatch mode, otherwise unmarshal always allocates new ones.
+// SBUnmarshaler implementations that mutate shared states are not isolated.
+func cloneForUnmarshal(ctx Ctx, value reflect.Value) reflect.Value {
+	return (&unmarshalCloner{
+		followPointers: ctx.opts().Merge == MergePatch,
+	}).clone(value)
+}
+
+type unmarshalCloner struct {
+	followPointers bool
+	pointers       map[clonedPointer]reflect.Value
+}
+
+type clonedPointer struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+func (c *unmarshalCloner) clone(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+
+	case reflect.Ptr:
+		if !c.followPointers || value.IsNil() {
+			return value
+		}
+		key := clonedPointer{
+			addr: value.Pointer(),
+			typ:  value.Type(),
+		}
+		if ret, ok := c.pointers[key]; ok {
+			return ret
+		}
+		if c.pointers == nil {
+			c.pointers = make(map[clonedPointer]reflect.Value)
+		}
+		ret := reflect.New(value.Type().Elem())
+		c.pointers[key] = ret
+		ret.Elem().Set(c.clone(value.Elem()))
+		return ret
+
+	case reflect.Struct:
+		ret := reflect.New(value.Type()).Elem()
+		ret.Set(value)
+		for i := 0; i < value.NumField(); i++ {
+			field := ret.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			field.Set(c.clone(field))
+		}
+		return ret
+
+	case reflect.Array:
+		ret := reflect.New(value.Type()).Elem()
+		for i := 0; i < value.Len(); i++ {
+			ret.Index(i).Set(c.clone(value.Index(i)))
+		}
+		return ret
+
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		ret := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			ret.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return ret
+
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		// appending will not write to the shared backing array
+		return value.Slice3(0, value.Len(), value.Len())
+
+	}
+	return value
+}
@@ -0,0 +1,137 @@
+package sb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalAtomic(t *testing.T) {
+	type Inner struct {
+		N [2]int
+	}
+	type Foo struct {
+		I     int
+		S     []int
+		M     map[string]int
+		Inner Inner
+		Last  string
+	}
+
+	newFoo := func() Foo {
+		s := make([]int, 1, 8)
+		s[0] = 1
+		return Foo{
+			I: 1,
+			S: s,
+			M: map[string]int{
+				"a": 1,
+			},
+			Inner: Inner{
+				N: [2]int{1, 1},
+			},
+			Last: "foo",
+		}
+	}
+
+	// type mismatch in the last field
+	bad := Tokens{
+		{Kind: KindObject},
+		{Kind: KindString, Value: "I"},
+		{Kind: KindInt, Value: 2},
+		{Kind: KindString, Value: "S"},
+		{Kind: KindArray},
+		{Kind: KindInt, Value: 2},
+		{Kind: KindArrayEnd},
+		{Kind: KindString, Value: "M"},
+		{Kind: KindMap},
+		{Kind: KindString, Value: "b"},
+		{Kind: KindInt, Value: 2},
+		{Kind: KindMapEnd},
+		{Kind: KindString, Value: "Inner"},
+		{Kind: KindObject},
+		{Kind: KindString, Value: "N"},
+		{Kind: KindArray},
+		{Kind: KindInt, Value: 2},
+		{Kind: KindInt, Value: 2},
+		{Kind: KindArrayEnd},
+		{Kind: KindObjectEnd},
+		{Kind: KindString, Value: "Last"},
+		{Kind: KindInt, Value: 2},
+		{Kind: KindObjectEnd},
+	}
+
+	// not atomic
+	foo := newFoo()
+	if err := Copy(bad.Iter(), Unmarshal(&foo)); !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+	if reflect.DeepEqual(foo, newFoo()) {
+		t.Fatal()
+	}
+
+	// atomic
+	foo = newFoo()
+	if err := Copy(bad.Iter(), UnmarshalAtomic(&foo)); !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+	if !reflect.DeepEqual(foo, newFoo()) {
+		t.Fatalf("got %+v", foo)
+	}
+	if s := foo.S[:2]; s[1] != 0 {
+		// backing array not written
+		t.Fatal()
+	}
+
+	// bad last element
+	var array [3]int
+	if err := Copy(
+		Tokens{
+			{Kind: KindArray},
+			{Kind: KindInt, Value: 1},
+			{Kind: KindInt, Value: 2},
+			{Kind: KindString, Value: "3"},
+			{Kind: KindArrayEnd},
+		}.Iter(),
+		UnmarshalValue(DefaultCtx.Atomic(), reflect.ValueOf(&array), nil),
+	); !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+	if array != [3]int{} {
+		t.Fatal()
+	}
+
+	// success
+	good := append(bad[:len(bad)-2:len(bad)-2], Tokens{
+		{Kind: KindString, Value: "bar"},
+		{Kind: KindObjectEnd},
+	}...)
+	if err := Copy(good.Iter(), UnmarshalAtomic(&foo)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(foo, Foo{
+		I: 2,
+		S: []int{1, 2},
+		M: map[string]int{
+			"a": 1,
+			"b": 2,
+		},
+		Inner: Inner{
+			N: [2]int{2, 2},
+		},
+		Last: "bar",
+	}) {
+		t.Fatalf("got %+v", foo)
+	}
+
+	// nested values
+	var foos []Foo
+	if err := Copy(
+		Marshal([]Foo{newFoo()}),
+		UnmarshalAtomic(&foos),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(foos) != 1 || !reflect.DeepEqual(foos[0], newFoo()) {
+		t.Fatal()
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/reusee/sb"
+)
+
+var jsonEndKinds = map[sb.Kind]sb.Kind{
+	sb.KindArray:  sb.KindArrayEnd,
+	sb.KindObject: sb.KindObjectEnd,
+	sb.KindMap:    sb.KindMapEnd,
+	sb.KindTuple:  sb.KindTupleEnd,
+}
+
+type jsonFrame struct {
+	kind sb.Kind
+	n    int
+}
+
+// encodeJSON writes each top-level value as a line of JSON
+// maps become objects with keys rendered as strings, tuples become arrays, bytes become base64 strings
+func encodeJSON(w io.Writer, stream sb.Stream) error {
+	var stack []jsonFrame
+	write := func(s string) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+
+	for {
+		var token sb.Token
+		if err := stream.Next(&token); err != nil {
+			return err
+		}
+		if token.Invalid() {
+			if len(stack) > 0 {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		}
+
+		switch token.Kind {
+		case sb.KindTypeName, sb.KindPointerID:
+			// prefixes are dropped
+			continue
+		case sb.KindArrayEnd, sb.KindObjectEnd, sb.KindMapEnd, sb.KindTupleEnd:
+			if len(stack) == 0 || jsonEndKinds[stack[len(stack)-1].kind] != token.Kind {
+				return sb.UnexpectedEndToken
+			}
+			stack = stack[:len(stack)-1]
+			var err error
+			if token.Kind == sb.KindArrayEnd || token.Kind == sb.KindTupleEnd {
+				err = write("]")
+			} else {
+				err = write("}")
+			}
+			if err != nil {
+				return err
+			}
+			if len(stack) == 0 {
+				if err := write("\n"); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		isKey := false
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			isObject := top.kind == sb.KindObject || top.kind == sb.KindMap
+			if isObject && top.n%2 == 1 {
+				if err := write(":"); err != nil {
+					return err
+				}
+			} else if top.n > 0 {
+				if err := write(","); err != nil {
+					return err
+				}
+			}
+			isKey = isObject && top.n%2 == 0
+			top.n++
+		}
+
+		switch token.Kind {
+		case sb.KindArray, sb.KindTuple:
+			if isKey {
+				return fmt.Errorf("unsupported map key: %s", token.Kind)
+			}
+			if err := write("["); err != nil {
+				return err
+			}
+			stack = append(stack, jsonFrame{kind: token.Kind})
+			continue
+		case sb.KindObject, sb.KindMap:
+			if isKey {
+				return fmt.Errorf("unsupported map key: %s", token.Kind)
+			}
+			if err := write("{"); err != nil {
+				return err
+			}
+			stack = append(stack, jsonFrame{kind: token.Kind})
+			continue
+		}
+
+		bs, err := jsonScalar(token)
+		if err != nil {
+			return err
+		}
+		if isKey && token.Kind != sb.KindString {
+			bs, err = json.Marshal(string(bs))
+			if err != nil { // NOCOVER
+				return err
+			}
+		}
+		if _, err := w.Write(bs); err != nil {
+			return err
+		}
+		if len(stack) == 0 {
+			if err := write("\n"); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func jsonScalar(token sb.Token) ([]byte, error) {
+	switch token.Kind {
+	case sb.KindNil:
+		return []byte("null"), nil
+	case sb.KindLiteral:
+		if s := token.Value.(string); json.Valid([]byte(s)) {
+			return []byte(s), nil
+		}
+	case sb.KindFloat32:
+		if f := float64(token.Value.(float32)); math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("unsupported float: %v", f)
+		}
+	case sb.KindFloat64:
+		if f := token.Value.(float64); math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("unsupported float: %v", f)
+		}
+	case sb.KindBool, sb.KindString, sb.KindBytes,
+		sb.KindInt, sb.KindInt8, sb.KindInt16, sb.KindInt32, sb.KindInt64,
+		sb.KindUint, sb.KindUint8, sb.KindUint16, sb.KindUint32, sb.KindUint64:
+	default:
+		return nil, fmt.Errorf("unsupported token: %s", token.Kind)
+	}
+	return json.Marshal(token.Value)
+}
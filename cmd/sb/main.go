@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/reusee/sb"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+type command struct {
+	usage string
+	run   func(args []string, stdin io.Reader, stdout io.Writer) error
+}
+
+var commands = map[string]command{
+	"dump":     {"dump [file]", dump},
+	"tojson":   {"tojson [file]", toJSON},
+	"fromjson": {"fromjson [file]", fromJSON},
+	"hash":     {"hash [-algo name] [file]", hashValues},
+	"cmp":      {"cmp file1 file2", cmp},
+	"validate": {"validate [file]", validate},
+	"find":     {"find [-algo name] hash [file]", find},
+}
+
+var errDiffer = fmt.Errorf("differ")
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage(stderr)
+		return 2
+	}
+	w := bufio.NewWriter(stdout)
+	err := cmd.run(args[1:], stdin, w)
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err == errDiffer {
+		return 1
+	} else if err != nil {
+		fmt.Fprintf(stderr, "sb %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "usage:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "\tsb %s\n", commands[name].usage)
+	}
+}
+
+func readInput(args []string, stdin io.Reader) ([]byte, error) {
+	if len(args) == 0 || args[0] == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(args[0])
+}
+
+type countingReader struct {
+	r *bytes.Reader
+	n int64
+}
+
+func (c *countingReader) Read(buf []byte) (int, error) {
+	n, err := c.r.Read(buf)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+func dump(args []string, stdin io.Reader, stdout io.Writer) error {
+	data, err := readInput(args, stdin)
+	if err != nil {
+		return err
+	}
+	r := &countingReader{
+		r: bytes.NewReader(data),
+	}
+	return printTokens(stdout, sb.Decode(r), func() int64 {
+		return r.n
+	})
+}
+
+// printTokens prints one token per line, indented by depth, prefixed by offset if offset is not nil
+func printTokens(w io.Writer, stream sb.Stream, offset func() int64) error {
+	depth := 0
+	for {
+		var pos int64
+		if offset != nil {
+			pos = offset()
+		}
+		var token sb.Token
+		if err := stream.Next(&token); err != nil {
+			return err
+		}
+		if token.Invalid() {
+			return nil
+		}
+		switch token.Kind {
+		case sb.KindArrayEnd, sb.KindObjectEnd, sb.KindMapEnd, sb.KindTupleEnd:
+			if depth > 0 {
+				depth--
+			}
+		}
+		if offset != nil {
+			if _, err := fmt.Fprintf(w, "%08d ", pos); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", depth), formatToken(token)); err != nil {
+			return err
+		}
+		switch token.Kind {
+		case sb.KindArray, sb.KindObject, sb.KindMap, sb.KindTuple:
+			depth++
+		}
+	}
+}
+
+func formatToken(token sb.Token) string {
+	kind := strings.TrimPrefix(token.Kind.String(), "Kind")
+	switch value := token.Value.(type) {
+	case nil:
+		return kind
+	case string:
+		return fmt.Sprintf("%s %q", kind, value)
+	case []byte:
+		return fmt.Sprintf("%s %x", kind, value)
+	default:
+		return fmt.Sprintf("%s %v", kind, value)
+	}
+}
+
+func toJSON(args []string, stdin io.Reader, stdout io.Writer) error {
+	data, err := readInput(args, stdin)
+	if err != nil {
+		return err
+	}
+	return encodeJSON(stdout, sb.Decode(bytes.NewReader(data)))
+}
+
+func fromJSON(args []string, stdin io.Reader, stdout io.Writer) error {
+	data, err := readInput(args, stdin)
+	if err != nil {
+		return err
+	}
+	return sb.Copy(
+		sb.DecodeJson(bytes.NewReader(data), nil),
+		sb.Encode(stdout),
+	)
+}
+
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":     md5.New,
+	"sha1":    sha1.New,
+	"sha256":  sha256.New,
+	"sha512":  sha512.New,
+	"fnv64":   func() hash.Hash { return fnv.New64() },
+	"fnv64a":  func() hash.Hash { return fnv.New64a() },
+	"fnv128":  fnv.New128,
+	"fnv128a": fnv.New128a,
+}
+
+func hashFlag(set *flag.FlagSet) *string {
+	var names []string
+	for name := range hashAlgorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return set.String("algo", "sha256", "hash algorithm: "+strings.Join(names, ", "))
+}
+
+func getHashAlgorithm(name string) (func() hash.Hash, error) {
+	newState, ok := hashAlgorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash algorithm: %s", name)
+	}
+	return newState, nil
+}
+
+func hashValues(args []string, stdin io.Reader, stdout io.Writer) error {
+	set := flag.NewFlagSet("hash", flag.ContinueOnError)
+	algo := hashFlag(set)
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	newState, err := getHashAlgorithm(*algo)
+	if err != nil {
+		return err
+	}
+	data, err := readInput(set.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		var sum []byte
+		if err := sb.Copy(
+			sb.Decode(r),
+			sb.Hash(newState, &sum, nil),
+		); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(stdout, "%x\n", sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cmp(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expecting two files")
+	}
+	a, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+	res, err := sb.CompareBytes(a, b)
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return nil
+	}
+
+	// locate first differing token
+	readerA := &countingReader{r: bytes.NewReader(a)}
+	readerB := &countingReader{r: bytes.NewReader(b)}
+	streamA := sb.Decode(readerA)
+	streamB := sb.Decode(readerB)
+	for {
+		offsetA, offsetB := readerA.n, readerB.n
+		var tokenA, tokenB sb.Token
+		if err := streamA.Next(&tokenA); err != nil {
+			return err
+		}
+		if err := streamB.Next(&tokenB); err != nil {
+			return err
+		}
+		if tokenA.Kind == tokenB.Kind && reflect.DeepEqual(tokenA.Value, tokenB.Value) &&
+			tokenA.Valid() {
+			continue
+		}
+		if _, err := fmt.Fprintf(
+			stdout,
+			"%s %s differ: offset %d %d, compare %d\n",
+			args[0], args[1],
+			offsetA, offsetB,
+			res,
+		); err != nil {
+			return err
+		}
+		return errDiffer
+	}
+}
+
+func validate(args []string, stdin io.Reader, stdout io.Writer) error {
+	data, err := readInput(args, stdin)
+	if err != nil {
+		return err
+	}
+	return sb.Copy(
+		sb.Validate(sb.Decode(bytes.NewReader(data))),
+		sb.Discard,
+	)
+}
+
+func find(args []string, stdin io.Reader, stdout io.Writer) error {
+	set := flag.NewFlagSet("find", flag.ContinueOnError)
+	algo := hashFlag(set)
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	newState, err := getHashAlgorithm(*algo)
+	if err != nil {
+		return err
+	}
+	if set.NArg() == 0 {
+		return fmt.Errorf("expecting hash")
+	}
+	sum, err := hex.DecodeString(set.Arg(0))
+	if err != nil {
+		return err
+	}
+	data, err := readInput(set.Args()[1:], stdin)
+	if err != nil {
+		return err
+	}
+	sub, err := sb.FindByHash(sb.Decode(bytes.NewReader(data)), sum, newState)
+	if err != nil {
+		return err
+	}
+	return printTokens(stdout, sub, nil)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/reusee/sb"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+type testValue struct {
+	Foo  int
+	Bar  string
+	Baz  []float64
+	Qux  map[int][]byte
+	Quux []any
+}
+
+var testData = testValue{
+	Foo:  42,
+	Bar:  "bar",
+	Baz:  []float64{1.5, -2},
+	Qux:  map[int][]byte{1: []byte("foo"), 2: nil},
+	Quux: []any{true, nil, int8(-1), uint16(2)},
+}
+
+func writeValue(t *testing.T, dir string, name string, value any) string {
+	buf := new(bytes.Buffer)
+	if err := sb.Copy(sb.Marshal(value), sb.Encode(buf)); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	value := writeValue(t, dir, "value.sb", testData)
+	other := testData
+	other.Baz = []float64{1.5, -3}
+	otherValue := writeValue(t, dir, "other.sb", other)
+	malformedPath := filepath.Join(dir, "malformed.sb")
+	if err := os.WriteFile(malformedPath, []byte{byte(sb.KindArray), byte(sb.KindMapEnd)}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var fooHash []byte
+	if err := sb.Copy(
+		sb.Marshal([]byte("foo")),
+		sb.Hash(hashAlgorithms["sha1"], &fooHash, nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		args  []string
+		stdin string
+		code  int
+	}{
+		{"dump", []string{"dump", value}, "", 0},
+		{"tojson", []string{"tojson", value}, "", 0},
+		{"fromjson", []string{"fromjson"}, `{"foo": [1, 2.5, "bar", null, true]}`, 0},
+		{"hash", []string{"hash", value}, "", 0},
+		{"hash_fnv128a", []string{"hash", "-algo", "fnv128a", value}, "", 0},
+		{"cmp_equal", []string{"cmp", value, value}, "", 0},
+		{"cmp", []string{"cmp", value, otherValue}, "", 1},
+		{"validate", []string{"validate", value}, "", 0},
+		{"find", []string{"find", "-algo", "sha1", fmt.Sprintf("%x", fooHash), value}, "", 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stdout := new(bytes.Buffer)
+			stderr := new(bytes.Buffer)
+			code := run(c.args, strings.NewReader(c.stdin), stdout, stderr)
+			if code != c.code {
+				t.Fatalf("got code %d: %s", code, stderr.String())
+			}
+			// file names vary between runs
+			output := bytes.ReplaceAll(stdout.Bytes(), []byte(dir), []byte("DIR"))
+			golden := filepath.Join("testdata", c.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, output, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(output, expected) {
+				t.Fatalf("got\n%s\nexpected\n%s", output, expected)
+			}
+		})
+	}
+
+	// errors
+	for _, args := range [][]string{
+		{},
+		{"foo"},
+		{"validate", malformedPath},
+		{"tojson", malformedPath},
+		{"hash", "-algo", "foo", value},
+		{"cmp", value},
+		{"find", "-algo", "sha1", "0000", value},
+	} {
+		stderr := new(bytes.Buffer)
+		if code := run(args, strings.NewReader(""), new(bytes.Buffer), stderr); code == 0 {
+			t.Fatalf("%v: should fail", args)
+		}
+	}
+}
+
+func TestFromJSONRoundTrip(t *testing.T) {
+	input := `{"foo":[1,2.5,"bar",null,true]}` + "\n"
+	encoded := new(bytes.Buffer)
+	if code := run([]string{"fromjson"}, strings.NewReader(input), encoded, os.Stderr); code != 0 {
+		t.Fatal()
+	}
+	output := new(bytes.Buffer)
+	if code := run([]string{"tojson"}, encoded, output, os.Stderr); code != 0 {
+		t.Fatal()
+	}
+	if output.String() != input {
+		t.Fatalf("got %s", output.String())
+	}
+}
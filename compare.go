@@ -263,7 +263,24 @@ func CompareBytes(a, b []byte) (int, error) {
 				return 1, nil
 			}
 
-		case KindInt, KindInt64, KindUint, KindUint64, KindPointer:
+		case KindInt, KindInt64, KindPointerID, KindPointerRef:
+			bs, err = readA(8)
+			if err != nil {
+				return 0, err
+			}
+			a1 := int64(binary.LittleEndian.Uint64(bs))
+			bs, err = readB(8)
+			if err != nil {
+				return 0, err
+			}
+			b1 := int64(binary.LittleEndian.Uint64(bs))
+			if a1 < b1 {
+				return -1, nil
+			} else if a1 > b1 {
+				return 1, nil
+			}
+
+		case KindUint, KindUint64, KindPointer:
 			bs, err = readA(8)
 			if err != nil {
 				return 0, err
@@ -280,7 +297,24 @@ func CompareBytes(a, b []byte) (int, error) {
 				return 1, nil
 			}
 
-		case KindInt8, KindUint8:
+		case KindInt8:
+			bs, err = readA(1)
+			if err != nil {
+				return 0, err
+			}
+			a1 := int8(bs[0])
+			bs, err = readB(1)
+			if err != nil {
+				return 0, err
+			}
+			b1 := int8(bs[0])
+			if a1 < b1 {
+				return -1, nil
+			} else if a1 > b1 {
+				return 1, nil
+			}
+
+		case KindUint8:
 			bs, err = readA(1)
 			if err != nil {
 				return 0, err
@@ -297,7 +331,24 @@ func CompareBytes(a, b []byte) (int, error) {
 				return 1, nil
 			}
 
-		case KindInt16, KindUint16:
+		case KindInt16:
+			bs, err = readA(2)
+			if err != nil {
+				return 0, err
+			}
+			a1 := int16(binary.LittleEndian.Uint16(bs))
+			bs, err = readB(2)
+			if err != nil {
+				return 0, err
+			}
+			b1 := int16(binary.LittleEndian.Uint16(bs))
+			if a1 < b1 {
+				return -1, nil
+			} else if a1 > b1 {
+				return 1, nil
+			}
+
+		case KindUint16:
 			bs, err = readA(2)
 			if err != nil {
 				return 0, err
@@ -314,7 +365,24 @@ func CompareBytes(a, b []byte) (int, error) {
 				return 1, nil
 			}
 
-		case KindInt32, KindUint32:
+		case KindInt32:
+			bs, err = readA(4)
+			if err != nil {
+				return 0, err
+			}
+			a1 := int32(binary.LittleEndian.Uint32(bs))
+			bs, err = readB(4)
+			if err != nil {
+				return 0, err
+			}
+			b1 := int32(binary.LittleEndian.Uint32(bs))
+			if a1 < b1 {
+				return -1, nil
+			} else if a1 > b1 {
+				return 1, nil
+			}
+
+		case KindUint32:
 			bs, err = readA(4)
 			if err != nil {
 				return 0, err
@@ -69,6 +69,11 @@ func TestCompare(t *testing.T) {
 		{uint16(42), uint16(84)},
 		{uint32(42), uint32(84)},
 		{uint64(42), uint64(84)},
+		{-1, 1},
+		{int8(-1), int8(1)},
+		{int16(-1), int16(1)},
+		{int32(-1), int32(1)},
+		{int64(-1), int64(1)},
 		{float32(42), float32(84)},
 		{float64(42), float64(84)},
 		{map[int]int{1: 1}, map[int]int{1: 42}},
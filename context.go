@@ -0,0 +1,199 @@
+package sb
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/reusee/e5"
+)
+
+func contextError(ctx context.Context, offset int64, path Path) error {
+	return we.With(
+		e5.With(Offset(offset)),
+		e5.With(path),
+	)(ctx.Err())
+}
+
+// CopyContext is Copy that stops when ctx is done
+// the returned error contains the TokenIndex and Path of the next token
+func CopyContext(ctx context.Context, stream Stream, sinks ...Sink) error {
+	var tracker pathTracker
+	var n int64
+	var proc Proc
+	proc = func(token *Token) (Proc, error) {
+		if ctx.Err() != nil {
+			return nil, we.With(
+				e5.With(TokenIndex(n)),
+				e5.With(tracker.Path()),
+			)(ctx.Err())
+		}
+		if err := stream.Next(token); err != nil {
+			return nil, err
+		}
+		if token.Invalid() {
+			return nil, nil
+		}
+		n++
+		tracker.Push(token)
+		return proc, nil
+	}
+	return Copy(&proc, sinks...)
+}
+
+// interrupter calls fn when ctx is done, only while armed
+type interrupter struct {
+	ctx  context.Context
+	fn   func()
+	stop func() bool
+}
+
+func (i *interrupter) arm() {
+	if i.stop == nil {
+		i.stop = context.AfterFunc(i.ctx, i.fn)
+	}
+}
+
+func (i *interrupter) disarm() {
+	if i.stop != nil {
+		i.stop()
+		i.stop = nil
+	}
+}
+
+type countingReader struct {
+	r          io.Reader
+	byteReader io.ByteReader
+	n          int64
+}
+
+func (c *countingReader) Read(buf []byte) (int, error) {
+	n, err := c.r.Read(buf)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.byteReader.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+func DecodeContext(ctx context.Context, r io.Reader) *Proc {
+	counter := &countingReader{
+		r: r,
+	}
+	var byteReader io.ByteReader
+	if br, ok := r.(io.ByteReader); ok {
+		counter.byteReader = br
+		byteReader = counter
+	}
+	decode := decodeBuffer(counter, byteReader, make([]byte, 8), false, nil, nil)
+
+	// interrupt blocking reads, armed until the current top-level value is decoded
+	interrupt := &interrupter{
+		ctx: ctx,
+		fn: func() {
+			if d, ok := r.(interface {
+				SetReadDeadline(time.Time) error
+			}); ok {
+				d.SetReadDeadline(time.Now())
+			} else if c, ok := r.(io.Closer); ok {
+				c.Close()
+			}
+		},
+	}
+
+	var tracker pathTracker
+	var proc Proc
+	proc = func(token *Token) (Proc, error) {
+		if ctx.Err() != nil {
+			interrupt.disarm()
+			return nil, contextError(ctx, counter.n, tracker.Path())
+		}
+		interrupt.arm()
+		var err error
+		decode, err = decode(token)
+		if err != nil {
+			interrupt.disarm()
+			if ctx.Err() != nil {
+				return nil, contextError(ctx, counter.n, tracker.Path())
+			}
+			return nil, err
+		}
+		if token.Valid() {
+			tracker.Push(token)
+		}
+		if decode == nil || len(tracker.frames) == 0 {
+			interrupt.disarm()
+		}
+		if decode == nil {
+			return nil, nil
+		}
+		return proc, nil
+	}
+	return &proc
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(buf []byte) (int, error) {
+	n, err := c.w.Write(buf)
+	c.n += int64(n)
+	return n, err
+}
+
+func EncodeContext(ctx context.Context, w io.Writer) Sink {
+	counter := &countingWriter{
+		w: w,
+	}
+	encode := EncodeBuffer(counter, make([]byte, 8), nil)
+
+	// interrupt blocking writes, armed until the current top-level value is encoded
+	interrupt := &interrupter{
+		ctx: ctx,
+		fn: func() {
+			if d, ok := w.(interface {
+				SetWriteDeadline(time.Time) error
+			}); ok {
+				d.SetWriteDeadline(time.Now())
+			} else if c, ok := w.(io.Closer); ok {
+				c.Close()
+			}
+		},
+	}
+
+	var tracker pathTracker
+	var sink Sink
+	sink = func(token *Token) (Sink, error) {
+		if ctx.Err() != nil {
+			interrupt.disarm()
+			return nil, contextError(ctx, counter.n, tracker.Path())
+		}
+		interrupt.arm()
+		var err error
+		encode, err = encode(token)
+		if err != nil {
+			interrupt.disarm()
+			if ctx.Err() != nil {
+				return nil, contextError(ctx, counter.n, tracker.Path())
+			}
+			return nil, err
+		}
+		if token.Invalid() {
+			interrupt.disarm()
+			return nil, nil
+		}
+		tracker.Push(token)
+		if len(tracker.frames) == 0 {
+			interrupt.disarm()
+		}
+		return sink, nil
+	}
+	return sink
+}
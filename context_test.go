@@ -0,0 +1,230 @@
+package sb
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testCloseRecorder struct {
+	io.Reader
+	io.Writer
+	closed atomic.Bool
+}
+
+func (t *testCloseRecorder) Close() error {
+	t.closed.Store(true)
+	return nil
+}
+
+func TestCopyContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := 0
+	var sink Sink
+	sink = func(token *Token) (Sink, error) {
+		n++
+		if n == 4 {
+			cancel()
+		}
+		return sink, nil
+	}
+	err := CopyContext(
+		ctx,
+		Marshal(map[string][]int{
+			"foo": {1, 2, 3, 4},
+		}),
+		sink,
+	)
+	if !is(err, context.Canceled) {
+		t.Fatal()
+	}
+	var index TokenIndex
+	if !as(err, &index) || index != 4 {
+		t.Fatal()
+	}
+	var path Path
+	if !as(err, &path) || path.String() != "/foo/1" {
+		t.Fatalf("got %v", path)
+	}
+
+	// segmented string is one value
+	for _, c := range []struct {
+		tokens Tokens
+		path   string
+	}{
+		{
+			Tokens{
+				{Kind: KindArray},
+				{Kind: KindStringBegin},
+				{Kind: KindString, Value: "a"},
+				{Kind: KindString, Value: "b"},
+				{Kind: KindStringEnd},
+				{Kind: KindInt, Value: 1},
+				{Kind: KindArrayEnd},
+			},
+			"/1",
+		},
+		{
+			Tokens{
+				{Kind: KindObject},
+				{Kind: KindStringBegin},
+				{Kind: KindString, Value: "fo"},
+				{Kind: KindString, Value: "o"},
+				{Kind: KindStringEnd},
+				{Kind: KindInt, Value: 1},
+				{Kind: KindObjectEnd},
+			},
+			"/foo",
+		},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		n := 0
+		var sink Sink
+		sink = func(token *Token) (Sink, error) {
+			n++
+			if n == 5 {
+				cancel()
+			}
+			return sink, nil
+		}
+		err := CopyContext(ctx, c.tokens.Iter(), sink)
+		cancel()
+		if !is(err, context.Canceled) {
+			t.Fatal()
+		}
+		var path Path
+		if !as(err, &path) || path.String() != c.path {
+			t.Fatalf("got %v", path)
+		}
+	}
+
+	// not canceled
+	var v map[string][]int
+	if err := CopyContext(
+		context.Background(),
+		Marshal(map[string][]int{
+			"foo": {1, 2, 3, 4},
+		}),
+		Unmarshal(&v),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(v["foo"]) != 4 {
+		t.Fatal()
+	}
+}
+
+func TestDecodeContext(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Copy(Marshal([]int{1, 2, 3}), Encode(buf)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	var ints []int
+	if err := Copy(
+		DecodeContext(context.Background(), bytes.NewReader(data)),
+		Unmarshal(&ints),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(ints) != 3 {
+		t.Fatal()
+	}
+
+	// cancel after decoded
+	ctx, cancel := context.WithCancel(context.Background())
+	recorder := &testCloseRecorder{
+		Reader: bytes.NewReader(data),
+	}
+	ints = nil
+	if err := Copy(
+		DecodeContext(ctx, recorder),
+		Unmarshal(&ints),
+	); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+	if recorder.closed.Load() {
+		t.Fatal()
+	}
+
+	// blocking reader
+	r, w := net.Pipe()
+	defer r.Close()
+	defer w.Close()
+	go func() {
+		w.Write(data[:5])
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	err := Copy(
+		DecodeContext(ctx, r),
+		Discard,
+	)
+	if !is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	var path Path
+	if !as(err, &path) || path.String() != "/0" {
+		t.Fatalf("got %v", path)
+	}
+	var offset Offset
+	if !as(err, &offset) || offset != 5 {
+		t.Fatalf("got %v", offset)
+	}
+}
+
+func TestEncodeContext(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Copy(
+		Marshal([]int{1, 2, 3}),
+		EncodeContext(context.Background(), buf),
+	); err != nil {
+		t.Fatal(err)
+	}
+	var ints []int
+	if err := Copy(Decode(buf), Unmarshal(&ints)); err != nil {
+		t.Fatal(err)
+	}
+	if len(ints) != 3 {
+		t.Fatal()
+	}
+
+	// cancel after encoded
+	ctx, cancel := context.WithCancel(context.Background())
+	recorder := &testCloseRecorder{
+		Writer: new(bytes.Buffer),
+	}
+	if err := Copy(
+		Marshal([]int{1, 2, 3}),
+		EncodeContext(ctx, recorder),
+	); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+	if recorder.closed.Load() {
+		t.Fatal()
+	}
+
+	// blocking writer
+	r, w := net.Pipe()
+	defer r.Close()
+	defer w.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	err := Copy(
+		Marshal([]int{1, 2, 3}),
+		EncodeContext(ctx, w),
+	)
+	if !is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+}
@@ -24,6 +24,58 @@ type Ctx struct {
 	detectCycleEnabled          bool
 
 	IgnoreFuncs bool
+
+	SharedPointers bool
+	pointers       *pointerTable
+
+	// rarely used options, behind a pointer to keep Ctx small
+	options *ctxOptions
+
+	skipValidate bool
+}
+
+// ctxOptions is shared by copies of Ctx, and must be copied before modifying, see Ctx.withOptions
+type ctxOptions struct {
+	// zero means no limit
+	MaxSliceLength int
+	MaxMapLength   int
+
+	// unmarshal into a copy of the target, set the target only if no error
+	AtomicUnmarshal bool
+
+	Merge      MergeMode
+	SliceMerge SliceMergePolicy
+
+	TypeCodecs TypeCodecs
+
+	// marshal OrderedMap entries sorted by keys, overrides UnsortedMaps
+	CanonicalMaps bool
+	// marshal builtin map entries in iteration order, output is not deterministic
+	UnsortedMaps bool
+
+	// errors returned by SBValidator
+	invalidValues *InvalidValues
+}
+
+var defaultCtxOptions ctxOptions
+
+// opts returns options of c, which must not be modified
+func (c Ctx) opts() *ctxOptions {
+	if c.options == nil {
+		return &defaultCtxOptions
+	}
+	return c.options
+}
+
+// withOptions returns a Ctx with a modified copy of options
+func (c Ctx) withOptions(fn func(*ctxOptions)) Ctx {
+	options := new(ctxOptions)
+	if c.options != nil {
+		*options = *c.options
+	}
+	fn(options)
+	c.options = options
+	return c
 }
 
 type Path []any
@@ -66,6 +118,40 @@ func (c Ctx) Strict() Ctx {
 	return c
 }
 
+func (c Ctx) SharePointers() Ctx {
+	c.SharedPointers = true
+	return c
+}
+
+// LimitCollections limits element counts of slices and maps in unmarshaling, zero means no limit
+func (c Ctx) LimitCollections(maxSliceLength, maxMapLength int) Ctx {
+	return c.withOptions(func(options *ctxOptions) {
+		options.MaxSliceLength = maxSliceLength
+		options.MaxMapLength = maxMapLength
+	})
+}
+
+// Atomic unmarshals into a copy of the target, and sets the target only if no error
+func (c Ctx) Atomic() Ctx {
+	return c.withOptions(func(options *ctxOptions) {
+		options.AtomicUnmarshal = true
+	})
+}
+
+// Canonical marshals OrderedMap entries sorted by keys, overrides Unsorted
+func (c Ctx) Canonical() Ctx {
+	return c.withOptions(func(options *ctxOptions) {
+		options.CanonicalMaps = true
+	})
+}
+
+// Unsorted marshals builtin map entries in iteration order, output is not deterministic
+func (c Ctx) Unsorted() Ctx {
+	return c.withOptions(func(options *ctxOptions) {
+		options.UnsortedMaps = true
+	})
+}
+
 func (c Ctx) WithPath(path any) Ctx {
 	c.Path = append(c.Path, path)
 	return c
@@ -12,21 +12,73 @@ import (
 )
 
 func DecodeBuffer(r io.Reader, byteReader io.ByteReader, buf []byte, cont Proc) Proc {
-	return decodeBuffer(r, byteReader, buf, false, cont)
+	return decodeBuffer(r, byteReader, buf, false, nil, cont)
 }
 
 func DecodeBufferForCompare(r io.Reader, byteReader io.ByteReader, buf []byte, cont Proc) Proc {
-	return decodeBuffer(r, byteReader, buf, true, cont)
+	return decodeBuffer(r, byteReader, buf, true, nil, cont)
 }
 
 var initDecodeStep = 8
 
 var MaxDecodeStringLength uint64 = 4 * 1024 * 1024 * 1024
 
-func decodeBuffer(r io.Reader, byteReader io.ByteReader, buf []byte, forCompare bool, cont Proc) Proc {
+// zero values mean no limit
+type DecodeOptions struct {
+	MaxDepth        int
+	MaxTokens       int64
+	MaxBytes        int64
+	MaxStringLength uint64
+	MaxBytesLength  uint64
+}
+
+func DecodeBufferWithOptions(r io.Reader, byteReader io.ByteReader, buf []byte, opts DecodeOptions, cont Proc) Proc {
+	return decodeBuffer(r, byteReader, buf, false, &opts, cont)
+}
+
+func decodeBuffer(r io.Reader, byteReader io.ByteReader, buf []byte, forCompare bool, opts *DecodeOptions, cont Proc) Proc {
 	var proc Proc
 	var offset int64
+	var depth int
+	var numTokens int64
+	maxStringLength := MaxDecodeStringLength
+	maxBytesLength := MaxDecodeStringLength
+	if opts != nil {
+		if opts.MaxStringLength > 0 && opts.MaxStringLength < maxStringLength {
+			maxStringLength = opts.MaxStringLength
+		}
+		if opts.MaxBytesLength > 0 && opts.MaxBytesLength < maxBytesLength {
+			maxBytesLength = opts.MaxBytesLength
+		}
+	}
 	proc = Proc(func(token *Token) (next Proc, err error) {
+		defer func() {
+			if err != nil || opts == nil || !token.Valid() {
+				return
+			}
+			if opts.MaxBytes > 0 && offset > opts.MaxBytes {
+				next = nil
+				err = we.With(e5.With(Offset(offset)), e5.With(TooManyBytes))(DecodeError)
+				return
+			}
+			switch token.Kind {
+			case KindArray, KindObject, KindMap, KindTuple:
+				depth++
+				if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+					next = nil
+					err = we.With(e5.With(Offset(offset)), e5.With(TooDeep))(DecodeError)
+					return
+				}
+			case KindArrayEnd, KindObjectEnd, KindMapEnd, KindTupleEnd:
+				depth--
+			}
+			numTokens++
+			if opts.MaxTokens > 0 && numTokens > opts.MaxTokens {
+				next = nil
+				err = we.With(e5.With(Offset(offset)), e5.With(TooManyTokens))(DecodeError)
+			}
+		}()
+
 		var kind Kind
 		if byteReader != nil {
 			if b, err := byteReader.ReadByte(); errors.Is(err, io.EOF) {
@@ -181,6 +233,14 @@ func decodeBuffer(r io.Reader, byteReader io.ByteReader, buf []byte, forCompare
 			}
 			value = uintptr(binary.LittleEndian.Uint64(buf[:8]))
 
+		case KindPointerID, KindPointerRef:
+			if _, err := io.ReadFull(r, buf[:8]); err != nil {
+				return nil, we.With(e5.With(DecodeError), e5.With(Offset(offset)))(err)
+			} else {
+				offset += 8
+			}
+			value = int(binary.LittleEndian.Uint64(buf[:8]))
+
 		case KindFloat32:
 			if _, err := io.ReadFull(r, buf[:4]); err != nil {
 				return nil, we.With(e5.With(DecodeError), e5.With(Offset(offset)))(err)
@@ -231,9 +291,12 @@ func decodeBuffer(r io.Reader, byteReader io.ByteReader, buf []byte, forCompare
 					return nil, we.With(e5.With(DecodeError), e5.With(Offset(offset)))(err)
 				}
 			}
-			if length > MaxDecodeStringLength {
+			if length > maxStringLength {
 				return nil, we.With(e5.With(Offset(offset)), e5.With(StringTooLong))(DecodeError)
 			}
+			if opts != nil && opts.MaxBytes > 0 && offset+int64(length) > opts.MaxBytes {
+				return nil, we.With(e5.With(Offset(offset)), e5.With(TooManyBytes))(DecodeError)
+			}
 
 			if forCompare {
 				length := int(length)
@@ -322,9 +385,12 @@ func decodeBuffer(r io.Reader, byteReader io.ByteReader, buf []byte, forCompare
 					return nil, we.With(e5.With(DecodeError), e5.With(Offset(offset)))(err)
 				}
 			}
-			if length > MaxDecodeStringLength {
+			if length > maxBytesLength {
 				return nil, we.With(e5.With(Offset(offset)), e5.With(BytesTooLong))(DecodeError)
 			}
+			if opts != nil && opts.MaxBytes > 0 && offset+int64(length) > opts.MaxBytes {
+				return nil, we.With(e5.With(Offset(offset)), e5.With(TooManyBytes))(DecodeError)
+			}
 
 			if forCompare {
 				length := int(length)
@@ -395,7 +461,16 @@ func decode(r io.Reader, forCompare bool) *Proc {
 	if rd, ok := r.(io.ByteReader); ok {
 		byteReader = rd
 	}
-	proc := decodeBuffer(r, byteReader, make([]byte, 8), forCompare, nil)
+	proc := decodeBuffer(r, byteReader, make([]byte, 8), forCompare, nil, nil)
+	return &proc
+}
+
+func DecodeWithOptions(r io.Reader, opts DecodeOptions) *Proc {
+	var byteReader io.ByteReader
+	if rd, ok := r.(io.ByteReader); ok {
+		byteReader = rd
+	}
+	proc := decodeBuffer(r, byteReader, make([]byte, 8), false, &opts, nil)
 	return &proc
 }
 
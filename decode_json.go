@@ -36,17 +36,17 @@ func DecodeJson(r io.Reader, cont Proc) *Proc {
 				token.Kind = KindObjectEnd
 				return proc, nil
 			default:
-				return nil, fmt.Errorf("bad delimiter rune: %v", token)
+				return nil, fmt.Errorf("bad delimiter rune: %v", jsonToken)
 			}
 
 		case bool:
 			token.Kind = KindBool
-			token.Value = token
+			token.Value = jsonToken
 			return proc, nil
 
 		case float64:
 			token.Kind = KindFloat64
-			token.Value = token
+			token.Value = jsonToken
 			return proc, nil
 
 		case json.Number:
@@ -56,7 +56,7 @@ func DecodeJson(r io.Reader, cont Proc) *Proc {
 
 		case string:
 			token.Kind = KindString
-			token.Value = token
+			token.Value = jsonToken
 			return proc, nil
 
 		case nil:
@@ -65,7 +65,7 @@ func DecodeJson(r io.Reader, cont Proc) *Proc {
 
 		}
 
-		return nil, fmt.Errorf("bad token type: %T", token)
+		return nil, fmt.Errorf("bad token type: %T", jsonToken)
 	}
 
 	return &proc
@@ -40,3 +40,24 @@ func TestDecodeJson(t *testing.T) {
 	}
 
 }
+
+func TestDecodeJsonTokenValues(t *testing.T) {
+	tokens, err := TokensFromStream(
+		DecodeJson(bytes.NewReader([]byte(`[true, 1.5, "foo"]`)), nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 5 {
+		t.Fatalf("got %+v", tokens)
+	}
+	if v, ok := tokens[1].Value.(bool); !ok || !v {
+		t.Fatalf("got %#v", tokens[1].Value)
+	}
+	if v, ok := tokens[2].Value.(string); !ok || v != "1.5" {
+		t.Fatalf("got %#v", tokens[2].Value)
+	}
+	if v, ok := tokens[3].Value.(string); !ok || v != "foo" {
+		t.Fatalf("got %#v", tokens[3].Value)
+	}
+}
@@ -80,3 +80,112 @@ func TestDecodeError(t *testing.T) {
 	}
 
 }
+
+func TestDecodeWithOptions(t *testing.T) {
+	encode := func(v any) []byte {
+		buf := new(bytes.Buffer)
+		if err := Copy(Marshal(v), Encode(buf)); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	nested := encode([][][]int{{{1}}})
+	if err := Copy(
+		DecodeWithOptions(bytes.NewReader(nested), DecodeOptions{
+			MaxDepth: 3,
+		}),
+		Discard,
+	); err != nil {
+		t.Fatal(err)
+	}
+	err := Copy(
+		DecodeWithOptions(bytes.NewReader(nested), DecodeOptions{
+			MaxDepth: 2,
+		}),
+		Discard,
+	)
+	if !is(err, DecodeError) || !is(err, TooDeep) {
+		t.Fatal()
+	}
+	var offset Offset
+	if !as(err, &offset) || offset != 3 {
+		t.Fatalf("got %v", offset)
+	}
+
+	ints := encode([]int{1, 2, 3})
+	if err := Copy(
+		DecodeWithOptions(bytes.NewReader(ints), DecodeOptions{
+			MaxTokens: 5,
+		}),
+		Discard,
+	); err != nil {
+		t.Fatal(err)
+	}
+	err = Copy(
+		DecodeWithOptions(bytes.NewReader(ints), DecodeOptions{
+			MaxTokens: 4,
+		}),
+		Discard,
+	)
+	if !is(err, TooManyTokens) {
+		t.Fatal()
+	}
+
+	if err := Copy(
+		DecodeWithOptions(bytes.NewReader(ints), DecodeOptions{
+			MaxBytes: int64(len(ints)),
+		}),
+		Discard,
+	); err != nil {
+		t.Fatal(err)
+	}
+	err = Copy(
+		DecodeWithOptions(bytes.NewReader(ints), DecodeOptions{
+			MaxBytes: int64(len(ints)) - 1,
+		}),
+		Discard,
+	)
+	if !is(err, TooManyBytes) {
+		t.Fatal()
+	}
+	err = Copy(
+		DecodeWithOptions(bytes.NewReader(encode("foobar")), DecodeOptions{
+			MaxBytes: 4,
+		}),
+		Discard,
+	)
+	if !is(err, TooManyBytes) {
+		t.Fatal()
+	}
+
+	str := encode("foobar")
+	if err := Copy(
+		DecodeWithOptions(bytes.NewReader(str), DecodeOptions{
+			MaxStringLength: 6,
+		}),
+		Discard,
+	); err != nil {
+		t.Fatal(err)
+	}
+	err = Copy(
+		DecodeWithOptions(bytes.NewReader(str), DecodeOptions{
+			MaxStringLength: 5,
+		}),
+		Discard,
+	)
+	if !is(err, StringTooLong) {
+		t.Fatal()
+	}
+
+	bs := encode([]byte("foobar"))
+	err = Copy(
+		DecodeWithOptions(bytes.NewReader(bs), DecodeOptions{
+			MaxBytesLength: 5,
+		}),
+		Discard,
+	)
+	if !is(err, BytesTooLong) {
+		t.Fatal()
+	}
+}
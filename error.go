@@ -10,6 +10,9 @@ var (
 	BadTokenKind = fmt.Errorf("bad token kind")
 
 	UnexpectedEndToken = fmt.Errorf("unexpected end token")
+
+	// returned by iteration callbacks to stop iterating
+	Break = fmt.Errorf("break")
 )
 
 // unmarshal
@@ -18,12 +21,18 @@ var UnmarshalError = fmt.Errorf("unmarshal error")
 
 var (
 	BadFieldName        = fmt.Errorf("bad field name")
+	BadPointerID        = fmt.Errorf("bad pointer id")
 	BadTargetType       = fmt.Errorf("bad target type")
 	BadTupleType        = fmt.Errorf("bad tuple type")
+	BadUnionTag         = fmt.Errorf("bad union tag")
 	DuplicatedFieldName = fmt.Errorf("duplicated field name")
 	TooManyElement      = fmt.Errorf("too many element")
 	TooFewElement       = fmt.Errorf("too few element")
 	UnknownFieldName    = fmt.Errorf("unknown field name")
+	SliceTooLong        = fmt.Errorf("slice too long")
+	MapTooLarge         = fmt.Errorf("map too large")
+	MissingField        = fmt.Errorf("missing field")
+	BadValue            = fmt.Errorf("bad value")
 )
 
 type ErrUnmarshalTypeMismatch struct {
@@ -49,7 +58,8 @@ func TypeMismatch(kind Kind, target reflect.Kind) ErrUnmarshalTypeMismatch {
 var MarshalError = fmt.Errorf("marshal error")
 
 var (
-	CyclicPointer = fmt.Errorf("cyclic pointer")
+	CyclicPointer   = fmt.Errorf("cyclic pointer")
+	BadUnionVariant = fmt.Errorf("bad union variant")
 )
 
 // decode
@@ -60,4 +70,36 @@ var (
 	StringTooLong   = fmt.Errorf("string too long")
 	BytesTooLong    = fmt.Errorf("bytes too long")
 	BadStringLength = fmt.Errorf("bad string length")
+	BadKeyEscape    = fmt.Errorf("bad key escape")
+	TooDeep         = fmt.Errorf("too deep")
+	TooManyTokens   = fmt.Errorf("too many tokens")
+	TooManyBytes    = fmt.Errorf("too many bytes")
+)
+
+// table
+
+var (
+	BadTable      = fmt.Errorf("bad table")
+	KeyNotInOrder = fmt.Errorf("key not in order")
+	TableClosed   = fmt.Errorf("table closed")
+)
+
+// log
+
+var (
+	LogCorrupted      = fmt.Errorf("log corrupted")
+	LogClosed         = fmt.Errorf("log closed")
+	LogRecordTooLarge = fmt.Errorf("log record too large")
+)
+
+// validate
+
+var ValidateError = fmt.Errorf("validate error")
+
+var (
+	BadObjectKey   = fmt.Errorf("bad object key")
+	BadSegment     = fmt.Errorf("bad segment")
+	BadTokenValue  = fmt.Errorf("bad token value")
+	DanglingPrefix = fmt.Errorf("dangling prefix")
+	OddMapTokens   = fmt.Errorf("odd map tokens")
 )
@@ -0,0 +1,145 @@
+package sb
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/reusee/e5"
+)
+
+type HasFieldDefaults interface {
+	// field name to default value
+	SBFieldDefaults() map[string]any
+}
+
+type HasRequiredFields interface {
+	SBRequiredFields() []string
+}
+
+var (
+	hasFieldDefaultsType  = reflect.TypeOf((*HasFieldDefaults)(nil)).Elem()
+	hasRequiredFieldsType = reflect.TypeOf((*HasRequiredFields)(nil)).Elem()
+)
+
+type fieldTag struct {
+	Nested   bool
+	Required bool
+	Default  Tokens
+}
+
+// parseFieldTag parses comma separated options in tag `sb`
+// default value is unmarshaled as a KindLiteral token, and must be the last option
+func parseFieldTag(tag reflect.StructTag) (ret fieldTag) {
+	s := tag.Get("sb")
+	for s != "" {
+		if value, ok := strings.CutPrefix(s, "default="); ok {
+			ret.Default = Tokens{
+				{Kind: KindLiteral, Value: value},
+			}
+			break
+		}
+		var option string
+		option, s, _ = strings.Cut(s, ",")
+		switch option {
+		case "nested":
+			ret.Nested = true
+		case "required":
+			ret.Required = true
+		}
+	}
+	return
+}
+
+// applyFieldRules applies rules of HasRequiredFields and HasFieldDefaults, implemented by t or *t
+func applyFieldRules(t reflect.Type, set *structFieldSet) {
+	ptrType := reflect.PointerTo(t)
+
+	if ptrType.Implements(hasRequiredFieldsType) {
+		names := reflect.New(t).Interface().(HasRequiredFields).SBRequiredFields()
+		for _, name := range names {
+			if i, ok := set.ByName[name]; ok {
+				set.Fields[i].Required = true
+			}
+		}
+	}
+
+	if ptrType.Implements(hasFieldDefaultsType) {
+		defaults := reflect.New(t).Interface().(HasFieldDefaults).SBFieldDefaults()
+		for name, value := range defaults {
+			i, ok := set.ByName[name]
+			if !ok {
+				continue
+			}
+			tokens, err := TokensFromStream(Marshal(value))
+			if err != nil {
+				panic(fmt.Errorf("bad default value for %v.%s: %w", t, name, err))
+			}
+			set.Fields[i].Default = tokens
+		}
+	}
+
+	for _, field := range set.Fields {
+		if field.Required || field.Default != nil {
+			set.TrackPresence = true
+			break
+		}
+	}
+}
+
+// MissingFields is the paths of absent required fields
+type MissingFields []Path
+
+var _ error = MissingFields{}
+
+func (m MissingFields) Error() string {
+	var b strings.Builder
+	b.WriteString("missing fields:")
+	for _, path := range m {
+		b.WriteString(" ")
+		b.WriteString(path.String())
+	}
+	return b.String()
+}
+
+// checkFieldPresence sets default values of absent fields, and reports absent required fields
+func checkFieldPresence(
+	ctx Ctx,
+	target reflect.Value,
+	fields *structFieldSet,
+	present []bool,
+) error {
+	var missing MissingFields
+	for i, field := range fields.Fields {
+		if present[i] {
+			continue
+		}
+		path := append(ctx.Path[:len(ctx.Path):len(ctx.Path)], field.Name)
+		if field.Required {
+			missing = append(missing, path)
+			continue
+		}
+		if field.Default != nil {
+			fieldCtx := ctx
+			fieldCtx.Path = path
+			fieldValue, err := fieldByIndexAlloc(target.Elem(), field.Index)
+			if err != nil {
+				return we.With(WithPath(fieldCtx), e5.With(UnmarshalError))(err)
+			}
+			if err := Copy(
+				field.Default.Iter(),
+				ctx.Unmarshal(
+					fieldCtx,
+					fieldValue.Addr(),
+					nil,
+				),
+			); err != nil {
+				return err
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return we.With(WithPath(ctx), MissingField, missing)(UnmarshalError)
+	}
+	return nil
+}
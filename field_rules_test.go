@@ -0,0 +1,205 @@
+package sb
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testFieldRules struct {
+	Name    string `sb:"required"`
+	Port    int    `sb:"default=8080"`
+	Debug   bool   `sb:"default=true"`
+	Host    string `sb:"default=a,b"`
+	Timeout float64
+	Tags    []string
+}
+
+var _ HasFieldDefaults = testFieldRules{}
+
+func (testFieldRules) SBFieldDefaults() map[string]any {
+	return map[string]any{
+		"Tags": []string{"foo"},
+	}
+}
+
+var _ HasRequiredFields = testFieldRules{}
+
+func (testFieldRules) SBRequiredFields() []string {
+	return []string{"Timeout"}
+}
+
+type testPtrFieldRules struct {
+	Name string
+	Port int
+}
+
+var _ HasFieldDefaults = new(testPtrFieldRules)
+
+func (*testPtrFieldRules) SBFieldDefaults() map[string]any {
+	return map[string]any{
+		"Port": 8080,
+	}
+}
+
+var _ HasRequiredFields = new(testPtrFieldRules)
+
+func (*testPtrFieldRules) SBRequiredFields() []string {
+	return []string{"Name"}
+}
+
+func TestFieldRules(t *testing.T) {
+	// defaults
+	var v testFieldRules
+	if err := Copy(
+		Marshal(struct {
+			Name    string
+			Timeout float64
+		}{
+			Name:    "foo",
+			Timeout: 1,
+		}),
+		Unmarshal(&v),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, testFieldRules{
+		Name:    "foo",
+		Port:    8080,
+		Debug:   true,
+		Host:    "a,b",
+		Timeout: 1,
+		Tags:    []string{"foo"},
+	}) {
+		t.Fatalf("got %+v", v)
+	}
+
+	// present fields are not overwritten
+	v = testFieldRules{}
+	if err := Copy(
+		Marshal(testFieldRules{
+			Name:    "foo",
+			Port:    1,
+			Timeout: 1,
+		}),
+		Unmarshal(&v),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if v.Port != 1 || v.Debug || v.Host != "" || len(v.Tags) != 0 {
+		t.Fatalf("got %+v", v)
+	}
+
+	// missing
+	type Outer struct {
+		Rules []testFieldRules
+	}
+	var outer Outer
+	err := Copy(
+		Marshal(struct {
+			Rules []struct{}
+		}{
+			Rules: []struct{}{{}},
+		}),
+		Unmarshal(&outer),
+	)
+	if !is(err, MissingField) {
+		t.Fatalf("got %v", err)
+	}
+	var missing MissingFields
+	if !as(err, &missing) {
+		t.Fatal()
+	}
+	if len(missing) != 2 ||
+		missing[0].String() != "/Rules/0/Name" ||
+		missing[1].String() != "/Rules/0/Timeout" {
+		t.Fatalf("got %v", missing)
+	}
+
+	// strict
+	err = Copy(
+		Marshal(struct {
+			Name    string
+			Timeout float64
+			Foo     int
+		}{}),
+		UnmarshalValue(DefaultCtx.Strict(), reflect.ValueOf(&v), nil),
+	)
+	if !is(err, UnknownFieldName) {
+		t.Fatalf("got %v", err)
+	}
+	err = Copy(
+		Marshal(struct {
+			Name string
+		}{}),
+		UnmarshalValue(DefaultCtx.Strict(), reflect.ValueOf(&v), nil),
+	)
+	if !is(err, MissingField) {
+		t.Fatalf("got %v", err)
+	}
+
+	// patch
+	v = testFieldRules{
+		Port: 1,
+	}
+	if err := Copy(
+		Marshal(struct {
+			Debug bool
+		}{}),
+		UnmarshalValue(DefaultCtx.Patch(SliceAppend), reflect.ValueOf(&v), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if v.Port != 1 {
+		t.Fatal()
+	}
+
+	// bad default
+	var bad struct {
+		I int `sb:"default=foo"`
+	}
+	if err := Copy(
+		Marshal(struct{}{}),
+		Unmarshal(&bad),
+	); !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestPtrFieldRules(t *testing.T) {
+	var v testPtrFieldRules
+	if err := Copy(
+		Marshal(struct {
+			Name string
+		}{
+			Name: "foo",
+		}),
+		Unmarshal(&v),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if v.Port != 8080 {
+		t.Fatalf("got %+v", v)
+	}
+
+	v = testPtrFieldRules{}
+	if err := Copy(
+		Marshal(struct{}{}),
+		Unmarshal(&v),
+	); !is(err, MissingField) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestParseFieldTag(t *testing.T) {
+	tag := parseFieldTag(`sb:"nested,required,default=a,b"`)
+	if !tag.Nested || !tag.Required {
+		t.Fatal()
+	}
+	if len(tag.Default) != 1 || tag.Default[0].Value != "a,b" {
+		t.Fatal()
+	}
+	tag = parseFieldTag(`json:"foo"`)
+	if tag.Nested || tag.Required || tag.Default != nil {
+		t.Fatal()
+	}
+}
@@ -1,8 +1,10 @@
 package sb
 
 import (
+	"bytes"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -38,6 +40,49 @@ func TestFuzzCorpus(t *testing.T) {
 	}
 }
 
+func TestFuzzCorpusWithLimits(t *testing.T) {
+	files, err := filepath.Glob("corpus/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DecodeOptions{
+		MaxDepth:        4,
+		MaxTokens:       64,
+		MaxBytes:        1024,
+		MaxStringLength: 32,
+		MaxBytesLength:  32,
+	}
+	ctx := DefaultCtx.LimitCollections(8, 8)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var limited any
+		err = Copy(
+			DecodeWithOptions(bytes.NewReader(data), opts),
+			UnmarshalValue(ctx, reflect.ValueOf(&limited), nil),
+		)
+		if err != nil {
+			if !is(err, DecodeError) && !is(err, UnmarshalError) {
+				t.Fatalf("%s: %v", file, err)
+			}
+			continue
+		}
+		// same result as unlimited decoding
+		var obj any
+		if err := Copy(
+			Decode(bytes.NewReader(data)),
+			Unmarshal(&obj),
+		); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if MustCompare(Marshal(obj), Marshal(limited)) != 0 {
+			t.Fatalf("%s: not equal", file)
+		}
+	}
+}
+
 func FuzzAllCorpus(f *testing.F) {
 
 	files, err := filepath.Glob("corpus/*")
@@ -92,6 +92,7 @@ func HashFunc(
 			KindUint32,
 			KindUint64,
 			KindPointer,
+			KindPointerRef,
 			KindFloat32,
 			KindFloat64:
 
@@ -194,6 +195,14 @@ func HashFunc(
 				if _, err := state.Write((buf)); err != nil {
 					return nil, err
 				}
+			case KindPointerRef:
+				var buf []byte
+				elem := bytesPool8.Get(&buf)
+				defer elem.Put()
+				binary.LittleEndian.PutUint64(buf, uint64(token.Value.(int)))
+				if _, err := state.Write(buf); err != nil {
+					return nil, err
+				}
 			case KindFloat32:
 				var buf []byte
 				elem := bytesPool8.Get(&buf)
@@ -244,9 +253,19 @@ func HashFunc(
 				},
 			), nil
 
-		case KindTypeName:
-			if _, err := io.WriteString(state, token.Value.(string)); err != nil { // NOCOVER
-				return nil, err
+		case KindTypeName, KindPointerID:
+			if token.Kind == KindTypeName {
+				if _, err := io.WriteString(state, token.Value.(string)); err != nil { // NOCOVER
+					return nil, err
+				}
+			} else {
+				var buf []byte
+				elem := bytesPool8.Get(&buf)
+				defer elem.Put()
+				binary.LittleEndian.PutUint64(buf, uint64(token.Value.(int)))
+				if _, err := state.Write(buf); err != nil {
+					return nil, err
+				}
 			}
 			t := token
 			var subHash []byte
@@ -0,0 +1,179 @@
+package sb
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// SBValidator is called after the value is unmarshaled
+// errors of all values are collected and returned as InvalidValues when the outermost value is done
+type SBValidator interface {
+	ValidateSB(ctx Ctx) error
+}
+
+// SBNormalizer is called on a pointer to a copy of the value before marshaling
+// the copy is shallow, in-place modifications to slices or maps are visible to the original value
+type SBNormalizer interface {
+	NormalizeSB(ctx Ctx) error
+}
+
+var (
+	sbValidatorType  = reflect.TypeOf((*SBValidator)(nil)).Elem()
+	sbNormalizerType = reflect.TypeOf((*SBNormalizer)(nil)).Elem()
+)
+
+var validatorTypes, normalizerTypes sync.Map
+
+type validatorInfo struct {
+	// the pointer type implements SBValidator
+	IsValidator bool
+	// values of the pointed type may contain validators
+	HasValidators bool
+}
+
+// getValidatorInfo returns validator info of t, a pointer type
+func getValidatorInfo(t reflect.Type) validatorInfo {
+	if v, ok := validatorTypes.Load(t); ok {
+		return v.(validatorInfo)
+	}
+	info := validatorInfo{
+		IsValidator:   t.Implements(sbValidatorType),
+		HasValidators: hasValidators(t.Elem(), make(map[reflect.Type]bool)),
+	}
+	validatorTypes.Store(t, info)
+	return info
+}
+
+// hasValidators reports whether values of t may contain validators
+// values unmarshaled into interface targets are not considered
+func hasValidators(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	if reflect.PointerTo(t).Implements(sbValidatorType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return hasValidators(t.Elem(), visited)
+	case reflect.Map:
+		return hasValidators(t.Key(), visited) || hasValidators(t.Elem(), visited)
+	case reflect.Struct:
+		for _, field := range getStructFields(t).Fields {
+			if hasValidators(field.Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isNormalizer reports whether the pointer type of t implements SBNormalizer
+func isNormalizer(t reflect.Type) bool {
+	if v, ok := normalizerTypes.Load(t); ok {
+		return v.(bool)
+	}
+	ret := t.Kind() != reflect.Ptr &&
+		t.Kind() != reflect.Interface &&
+		reflect.PointerTo(t).Implements(sbNormalizerType)
+	normalizerTypes.Store(t, ret)
+	return ret
+}
+
+func normalizeValue(ctx Ctx, value reflect.Value) (reflect.Value, error) {
+	ptr := reflect.New(value.Type())
+	ptr.Elem().Set(value)
+	if err := ptr.Interface().(SBNormalizer).NormalizeSB(ctx); err != nil {
+		return reflect.Value{}, err
+	}
+	return ptr.Elem(), nil
+}
+
+type InvalidValue struct {
+	Path Path
+	Err  error
+}
+
+var _ error = InvalidValue{}
+
+func (i InvalidValue) Error() string {
+	return i.Path.String() + ": " + i.Err.Error()
+}
+
+func (i InvalidValue) Unwrap() error {
+	return i.Err
+}
+
+type InvalidValues []InvalidValue
+
+var _ error = InvalidValues{}
+
+func (i InvalidValues) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid values:")
+	for _, v := range i {
+		b.WriteString(" ")
+		b.WriteString(v.Error())
+		b.WriteString(";")
+	}
+	return b.String()
+}
+
+func (i InvalidValues) Unwrap() []error {
+	ret := make([]error, 0, len(i))
+	for _, v := range i {
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+// unmarshalValidated collects validation errors of sub values
+func unmarshalValidated(ctx Ctx, target reflect.Value, cont Sink) Sink {
+	invalid := new(InvalidValues)
+	ctx = ctx.withOptions(func(options *ctxOptions) {
+		options.invalidValues = invalid
+	})
+	return ctx.Unmarshal(ctx, target, func(token *Token) (Sink, error) {
+		if err := invalid.err(ctx); err != nil {
+			return nil, err
+		}
+		return cont.Sink(token)
+	})
+}
+
+func unmarshalValidator(ctx Ctx, target reflect.Value, cont Sink) Sink {
+	invalid := ctx.opts().invalidValues
+	root := invalid == nil
+	if root {
+		// not collected by outer values
+		invalid = new(InvalidValues)
+		ctx = ctx.withOptions(func(options *ctxOptions) {
+			options.invalidValues = invalid
+		})
+	}
+	valueCtx := ctx
+	valueCtx.skipValidate = true
+	return ctx.Unmarshal(valueCtx, target, func(token *Token) (Sink, error) {
+		if err := target.Interface().(SBValidator).ValidateSB(ctx); err != nil {
+			*invalid = append(*invalid, InvalidValue{
+				Path: append(ctx.Path[:0:0], ctx.Path...),
+				Err:  err,
+			})
+		}
+		if root {
+			if err := invalid.err(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return cont.Sink(token)
+	})
+}
+
+func (i *InvalidValues) err(ctx Ctx) error {
+	if len(*i) == 0 {
+		return nil
+	}
+	return we.With(WithPath(ctx), BadValue, *i)(UnmarshalError)
+}
@@ -0,0 +1,194 @@
+package sb
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+type testValidatedPort int
+
+var _ SBValidator = new(testValidatedPort)
+
+func (p *testValidatedPort) ValidateSB(ctx Ctx) error {
+	if *p <= 0 || *p > 65535 {
+		return fmt.Errorf("bad port: %d", *p)
+	}
+	return nil
+}
+
+type testValidatedServer struct {
+	ID   string
+	Port testValidatedPort
+}
+
+var _ SBValidator = new(testValidatedServer)
+
+func (s *testValidatedServer) ValidateSB(ctx Ctx) error {
+	if s.ID == "" {
+		return fmt.Errorf("empty id")
+	}
+	return nil
+}
+
+type testNormalizedSet []string
+
+var _ SBNormalizer = new(testNormalizedSet)
+
+func (s *testNormalizedSet) NormalizeSB(ctx Ctx) error {
+	*s = slices.Clone(*s)
+	slices.Sort(*s)
+	*s = slices.Compact(*s)
+	return nil
+}
+
+type testBadNormalizer struct{}
+
+func (*testBadNormalizer) NormalizeSB(ctx Ctx) error {
+	return fmt.Errorf("bad")
+}
+
+func TestValidator(t *testing.T) {
+	// valid
+	var servers []testValidatedServer
+	if err := Copy(
+		Marshal([]testValidatedServer{
+			{ID: "a", Port: 1},
+		}),
+		Unmarshal(&servers),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// aggregated
+	servers = nil
+	err := Copy(
+		Marshal([]testValidatedServer{
+			{ID: "a", Port: 1},
+			{ID: "", Port: 2},
+			{ID: "c", Port: 0},
+		}),
+		Unmarshal(&servers),
+	)
+	if !is(err, BadValue) {
+		t.Fatalf("got %v", err)
+	}
+	var invalid InvalidValues
+	if !as(err, &invalid) {
+		t.Fatal()
+	}
+	var paths []string
+	for _, v := range invalid {
+		paths = append(paths, v.Path.String())
+	}
+	if !slices.Equal(paths, []string{"/1", "/2/Port"}) {
+		t.Fatalf("got %v", paths)
+	}
+
+	// top-level validator
+	var server testValidatedServer
+	err = Copy(
+		Marshal(testValidatedServer{
+			Port: 70000,
+		}),
+		Unmarshal(&server),
+	)
+	if !as(err, &invalid) {
+		t.Fatalf("got %v", err)
+	}
+	if len(invalid) != 2 || invalid[0].Path.String() != "/Port" || invalid[1].Path.String() != "" {
+		t.Fatalf("got %v", invalid)
+	}
+
+	// chained values
+	var port testValidatedPort
+	var portPtr *testValidatedPort
+	var i int
+	if err := Copy(
+		ConcatStreams(Marshal(1), Marshal(2), Marshal(3)),
+		UnmarshalValue(DefaultCtx, reflect.ValueOf(&port), func(token *Token) (Sink, error) {
+			return UnmarshalValue(DefaultCtx, reflect.ValueOf(&portPtr), func(token *Token) (Sink, error) {
+				return Unmarshal(&i)(token)
+			})(token)
+		}),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if port != 1 || portPtr == nil || *portPtr != 2 || i != 3 {
+		t.Fatalf("got %v %v %v", port, portPtr, i)
+	}
+	i = 0
+	err = Copy(
+		ConcatStreams(Marshal(0), Marshal(2)),
+		UnmarshalValue(DefaultCtx, reflect.ValueOf(&portPtr), func(token *Token) (Sink, error) {
+			return Unmarshal(&i)(token)
+		}),
+	)
+	if !is(err, BadValue) {
+		t.Fatalf("got %v", err)
+	}
+	if i != 0 {
+		t.Fatal()
+	}
+
+	// in map values
+	var m map[string]testValidatedPort
+	err = Copy(
+		Marshal(map[string]int{
+			"a": 0,
+			"b": 1,
+		}),
+		UnmarshalValue(DefaultCtx, reflect.ValueOf(&m), nil),
+	)
+	if !as(err, &invalid) {
+		t.Fatalf("got %v", err)
+	}
+	if len(invalid) != 1 || invalid[0].Path.String() != "/a" {
+		t.Fatalf("got %v", invalid)
+	}
+}
+
+func TestNormalizer(t *testing.T) {
+	type Foo struct {
+		Set testNormalizedSet
+	}
+	foo := Foo{
+		Set: testNormalizedSet{"c", "a", "b", "a"},
+	}
+	res, err := Compare(
+		Marshal(foo),
+		Marshal(Foo{
+			Set: testNormalizedSet{"a", "b", "c"},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != 0 {
+		t.Fatal()
+	}
+	// original value not changed
+	if !slices.Equal(foo.Set, testNormalizedSet{"c", "a", "b", "a"}) {
+		t.Fatal()
+	}
+	// pointer
+	res, err = Compare(
+		Marshal(&foo.Set),
+		Marshal([]string{"a", "b", "c"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != 0 {
+		t.Fatal()
+	}
+
+	// error
+	if err := Copy(
+		Marshal([]testBadNormalizer{{}}),
+		Discard,
+	); !is(err, MarshalError) {
+		t.Fatalf("got %v", err)
+	}
+}
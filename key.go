@@ -0,0 +1,366 @@
+package sb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/reusee/e5"
+)
+
+// key encoding: bytes.Compare on encoded keys gives the same order as Compare on token streams
+// integers are big-endian with sign bit flipped
+// floats are big-endian with sign bit flipped for positives and all bits flipped for negatives
+// strings and bytes are escaped, 0x00 as 0x00 0xff, and terminated by 0x00 0x01
+// kinds without values, including string and bytes segment markers, are encoded as the kind byte only
+
+const (
+	keyEscape     = 0x00
+	keyEscaped00  = 0xff
+	keyTerminator = 0x01
+)
+
+func EncodeKey(w io.Writer) Sink {
+	return EncodeKeyBuffer(w, make([]byte, 0, 64), nil)
+}
+
+func EncodeKeyBuffer(w io.Writer, buf []byte, cont Sink) Sink {
+	var sink Sink
+	sink = func(token *Token) (Sink, error) {
+		if token.Invalid() {
+			return cont, nil
+		}
+		buf = appendKey(buf[:0], token)
+		if _, err := w.Write(buf); err != nil {
+			return nil, err
+		}
+		return sink, nil
+	}
+	return sink
+}
+
+func appendKey(buf []byte, token *Token) []byte {
+	buf = append(buf, byte(token.Kind))
+	if token.Value == nil {
+		return buf
+	}
+	switch value := token.Value.(type) {
+
+	case bool:
+		if value {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+
+	case int:
+		buf = binary.BigEndian.AppendUint64(buf, uint64(value)^(1<<63))
+
+	case int8:
+		buf = append(buf, uint8(value)^(1<<7))
+
+	case int16:
+		buf = binary.BigEndian.AppendUint16(buf, uint16(value)^(1<<15))
+
+	case int32:
+		buf = binary.BigEndian.AppendUint32(buf, uint32(value)^(1<<31))
+
+	case int64:
+		buf = binary.BigEndian.AppendUint64(buf, uint64(value)^(1<<63))
+
+	case uint:
+		buf = binary.BigEndian.AppendUint64(buf, uint64(value))
+
+	case uintptr:
+		buf = binary.BigEndian.AppendUint64(buf, uint64(value))
+
+	case uint8:
+		buf = append(buf, value)
+
+	case uint16:
+		buf = binary.BigEndian.AppendUint16(buf, value)
+
+	case uint32:
+		buf = binary.BigEndian.AppendUint32(buf, value)
+
+	case uint64:
+		buf = binary.BigEndian.AppendUint64(buf, value)
+
+	case float32:
+		if value == 0 {
+			// Compare treats -0 and +0 as equal
+			value = 0
+		}
+		bits := math.Float32bits(value)
+		if bits&(1<<31) != 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 31
+		}
+		buf = binary.BigEndian.AppendUint32(buf, bits)
+
+	case float64:
+		if value == 0 {
+			// Compare treats -0 and +0 as equal
+			value = 0
+		}
+		bits := math.Float64bits(value)
+		if bits&(1<<63) != 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 63
+		}
+		buf = binary.BigEndian.AppendUint64(buf, bits)
+
+	case string:
+		for i := 0; i < len(value); i++ {
+			if value[i] == keyEscape {
+				buf = append(buf, keyEscape, keyEscaped00)
+			} else {
+				buf = append(buf, value[i])
+			}
+		}
+		buf = append(buf, keyEscape, keyTerminator)
+
+	case []byte:
+		for _, b := range value {
+			if b == keyEscape {
+				buf = append(buf, keyEscape, keyEscaped00)
+			} else {
+				buf = append(buf, b)
+			}
+		}
+		buf = append(buf, keyEscape, keyTerminator)
+
+	default: // NOCOVER
+		panic(fmt.Errorf("bad type %#v %T", value, value))
+
+	}
+	return buf
+}
+
+func DecodeKey(r io.Reader) *Proc {
+	byteReader, ok := r.(io.ByteReader)
+	if !ok {
+		byteReader = singleByteReader{r}
+	}
+	proc := DecodeKeyBuffer(r, byteReader, make([]byte, 8), nil)
+	return &proc
+}
+
+type singleByteReader struct {
+	io.Reader
+}
+
+func (s singleByteReader) ReadByte() (byte, error) {
+	var buf [1]byte
+	if _, err := io.ReadFull(s.Reader, buf[:]); err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+func DecodeKeyBuffer(r io.Reader, byteReader io.ByteReader, buf []byte, cont Proc) Proc {
+	var proc Proc
+	var offset int64
+
+	readFull := func(l int) ([]byte, error) {
+		if _, err := io.ReadFull(r, buf[:l]); err != nil {
+			return nil, we.With(e5.With(DecodeError), e5.With(Offset(offset)))(err)
+		}
+		offset += int64(l)
+		return buf[:l], nil
+	}
+
+	readEscaped := func() ([]byte, error) {
+		var ret []byte
+		for {
+			b, err := byteReader.ReadByte()
+			if err != nil {
+				return nil, we.With(e5.With(DecodeError), e5.With(Offset(offset)))(err)
+			}
+			offset++
+			if b != keyEscape {
+				ret = append(ret, b)
+				continue
+			}
+			b, err = byteReader.ReadByte()
+			if err != nil {
+				return nil, we.With(e5.With(DecodeError), e5.With(Offset(offset)))(err)
+			}
+			offset++
+			switch b {
+			case keyEscaped00:
+				ret = append(ret, keyEscape)
+			case keyTerminator:
+				if uint64(len(ret)) > MaxDecodeStringLength {
+					return nil, we.With(e5.With(Offset(offset)), e5.With(StringTooLong))(DecodeError)
+				}
+				return ret, nil
+			default:
+				return nil, we.With(e5.With(Offset(offset)), e5.With(BadKeyEscape))(DecodeError)
+			}
+		}
+	}
+
+	proc = func(token *Token) (Proc, error) {
+		b, err := byteReader.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return cont, nil
+		} else if err != nil {
+			return nil, we.With(e5.With(DecodeError), e5.With(Offset(offset)))(err)
+		}
+		offset++
+		kind := Kind(b)
+
+		var value any
+		switch kind {
+
+		case KindBool:
+			bs, err := readFull(1)
+			if err != nil {
+				return nil, err
+			}
+			value = bs[0] > 0
+
+		case KindInt, KindPointerID, KindPointerRef:
+			bs, err := readFull(8)
+			if err != nil {
+				return nil, err
+			}
+			value = int(binary.BigEndian.Uint64(bs) ^ (1 << 63))
+
+		case KindInt8:
+			bs, err := readFull(1)
+			if err != nil {
+				return nil, err
+			}
+			value = int8(bs[0] ^ (1 << 7))
+
+		case KindInt16:
+			bs, err := readFull(2)
+			if err != nil {
+				return nil, err
+			}
+			value = int16(binary.BigEndian.Uint16(bs) ^ (1 << 15))
+
+		case KindInt32:
+			bs, err := readFull(4)
+			if err != nil {
+				return nil, err
+			}
+			value = int32(binary.BigEndian.Uint32(bs) ^ (1 << 31))
+
+		case KindInt64:
+			bs, err := readFull(8)
+			if err != nil {
+				return nil, err
+			}
+			value = int64(binary.BigEndian.Uint64(bs) ^ (1 << 63))
+
+		case KindUint:
+			bs, err := readFull(8)
+			if err != nil {
+				return nil, err
+			}
+			value = uint(binary.BigEndian.Uint64(bs))
+
+		case KindUint8:
+			bs, err := readFull(1)
+			if err != nil {
+				return nil, err
+			}
+			value = bs[0]
+
+		case KindUint16:
+			bs, err := readFull(2)
+			if err != nil {
+				return nil, err
+			}
+			value = binary.BigEndian.Uint16(bs)
+
+		case KindUint32:
+			bs, err := readFull(4)
+			if err != nil {
+				return nil, err
+			}
+			value = binary.BigEndian.Uint32(bs)
+
+		case KindUint64:
+			bs, err := readFull(8)
+			if err != nil {
+				return nil, err
+			}
+			value = binary.BigEndian.Uint64(bs)
+
+		case KindPointer:
+			bs, err := readFull(8)
+			if err != nil {
+				return nil, err
+			}
+			value = uintptr(binary.BigEndian.Uint64(bs))
+
+		case KindFloat32:
+			bs, err := readFull(4)
+			if err != nil {
+				return nil, err
+			}
+			bits := binary.BigEndian.Uint32(bs)
+			if bits&(1<<31) != 0 {
+				bits &^= 1 << 31
+			} else {
+				bits = ^bits
+			}
+			value = math.Float32frombits(bits)
+
+		case KindFloat64:
+			bs, err := readFull(8)
+			if err != nil {
+				return nil, err
+			}
+			bits := binary.BigEndian.Uint64(bs)
+			if bits&(1<<63) != 0 {
+				bits &^= 1 << 63
+			} else {
+				bits = ^bits
+			}
+			value = math.Float64frombits(bits)
+
+		case KindString, KindTypeName, KindLiteral:
+			bs, err := readEscaped()
+			if err != nil {
+				return nil, err
+			}
+			value = string(bs)
+
+		case KindBytes, KindRef:
+			bs, err := readEscaped()
+			if err != nil {
+				return nil, err
+			}
+			if bs == nil {
+				bs = []byte{}
+			}
+			value = bs
+
+		case KindMin,
+			KindArrayEnd, KindObjectEnd, KindMapEnd, KindTupleEnd,
+			KindStringBegin, KindStringEnd, KindBytesBegin, KindBytesEnd,
+			KindNil, KindNaN,
+			KindArray, KindObject, KindMap, KindTuple,
+			KindMax:
+
+		default:
+			return nil, we.With(e5.With(Offset(offset)), e5.With(BadTokenKind), e5.With(kind))(DecodeError)
+
+		}
+
+		token.Kind = kind
+		token.Value = value
+		return proc, nil
+	}
+
+	return proc
+}
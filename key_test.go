@@ -0,0 +1,106 @@
+package sb
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestKeyOrder(t *testing.T) {
+	type Foo struct {
+		I  int
+		S  string
+		BS []byte
+	}
+	values := []any{
+		nil, true, false,
+		-1, 0, 1, math.MinInt, math.MaxInt,
+		int8(-1), int8(1), int16(-300), int16(300),
+		int32(-1), int32(1), int64(-1), int64(1),
+		uint(0), uint(1), uint8(1), uint8(255),
+		uint16(1), uint32(1), uint64(math.MaxUint64),
+		float32(-1.5), float32(0), float32(1.5),
+		-1.5, 0.0, math.Copysign(0, -1), 1.5, math.Inf(1), math.Inf(-1), math.NaN(),
+		"", "a", "ab", "a\x00", "a\x00b", "\x00", "\xff",
+		[]byte{}, []byte{0}, []byte{0, 0}, []byte{1},
+		[]int{}, []int{1}, []int{1, 2}, []int{2},
+		Foo{}, Foo{I: 1}, Foo{I: 1, S: "a"}, Foo{S: "a\x00"},
+		map[string]int{}, map[string]int{"a": 1}, map[string]int{"a": 1, "b": 2},
+		Tuple{1, "a"}, Tuple{1}, Min, Max,
+		[]any{1, "a", nil, []byte("b")},
+		// segmented strings and bytes
+		Tokens{
+			{Kind: KindStringBegin},
+			{Kind: KindString, Value: "a"},
+			{Kind: KindString, Value: "b"},
+			{Kind: KindStringEnd},
+		},
+		Tokens{
+			{Kind: KindStringBegin},
+			{Kind: KindString, Value: "a"},
+			{Kind: KindStringEnd},
+		},
+		Tokens{
+			{Kind: KindBytesBegin},
+			{Kind: KindBytes, Value: []byte{0}},
+			{Kind: KindBytesEnd},
+		},
+	}
+	stream := func(v any) Stream {
+		if tokens, ok := v.(Tokens); ok {
+			return tokens.Iter()
+		}
+		return Marshal(v)
+	}
+	for i := 0; i < 100; i++ {
+		values = append(values, rand.Int63()-rand.Int63(), rand.Float64()-0.5)
+	}
+
+	keys := make([][]byte, len(values))
+	for i, v := range values {
+		buf := new(bytes.Buffer)
+		if err := Copy(stream(v), EncodeKey(buf)); err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = buf.Bytes()
+
+		// round trip
+		if MustCompare(DecodeKey(bytes.NewReader(keys[i])), stream(v)) != 0 {
+			t.Fatalf("bad round trip: %#v", v)
+		}
+		if MustCompare(DecodeKey(struct{ *bytes.Reader }{bytes.NewReader(keys[i])}), stream(v)) != 0 {
+			t.Fatalf("bad round trip: %#v", v)
+		}
+	}
+
+	for i, a := range values {
+		for j, b := range values {
+			expected := MustCompare(stream(a), stream(b))
+			if res := bytes.Compare(keys[i], keys[j]); res != expected {
+				t.Fatalf("%#v %#v: expected %d, got %d", a, b, expected, res)
+			}
+		}
+	}
+}
+
+func TestBadDecodeKey(t *testing.T) {
+	for _, bs := range [][]byte{
+		{byte(KindInt), 1},
+		{byte(KindString), 'a'},
+		{byte(KindString), 0},
+		{byte(KindString), 0, 2},
+		{2},
+		{byte(KindStringBegin), byte(KindString), 'a'},
+		{byte(KindBytesBegin), byte(KindBytes), 0},
+	} {
+		err := Copy(DecodeKey(bytes.NewReader(bs)), Discard)
+		if !is(err, DecodeError) {
+			t.Fatalf("expecting error: %v", bs)
+		}
+	}
+	err := Copy(DecodeKey(bytes.NewReader([]byte{byte(KindString), 0, 2})), Discard)
+	if !is(err, BadKeyEscape) {
+		t.Fatal()
+	}
+}
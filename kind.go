@@ -45,6 +45,9 @@ const (
 	KindLiteral  Kind = 240
 	KindPointer  Kind = 245
 
+	KindPointerID  Kind = 247
+	KindPointerRef Kind = 248
+
 	KindRef Kind = 251
 
 	KindMax Kind = 0xFF
@@ -42,11 +42,13 @@ func _() {
 	_ = x[KindTypeName-230]
 	_ = x[KindLiteral-240]
 	_ = x[KindPointer-245]
+	_ = x[KindPointerID-247]
+	_ = x[KindPointerRef-248]
 	_ = x[KindRef-251]
 	_ = x[KindMax-255]
 }
 
-const _Kind_name = "KindInvalidKindMinKindArrayEndKindObjectEndKindMapEndKindTupleEndKindNilKindBoolKindStringEndKindStringKindStringBeginKindBytesEndKindBytesKindBytesBeginKindIntKindInt8KindInt16KindInt32KindInt64KindUintKindUint8KindUint16KindUint32KindUint64KindFloat32KindFloat64KindNaNKindArrayKindObjectKindMapKindTupleKindTypeNameKindLiteralKindPointerKindRefKindMax"
+const _Kind_name = "KindInvalidKindMinKindArrayEndKindObjectEndKindMapEndKindTupleEndKindNilKindBoolKindStringEndKindStringKindStringBeginKindBytesEndKindBytesKindBytesBeginKindIntKindInt8KindInt16KindInt32KindInt64KindUintKindUint8KindUint16KindUint32KindUint64KindFloat32KindFloat64KindNaNKindArrayKindObjectKindMapKindTupleKindTypeNameKindLiteralKindPointerKindPointerIDKindPointerRefKindRefKindMax"
 
 var _Kind_map = map[Kind]string{
 	0:   _Kind_name[0:11],
@@ -83,8 +85,10 @@ var _Kind_map = map[Kind]string{
 	230: _Kind_name[306:318],
 	240: _Kind_name[318:329],
 	245: _Kind_name[329:340],
-	251: _Kind_name[340:347],
-	255: _Kind_name[347:354],
+	247: _Kind_name[340:353],
+	248: _Kind_name[353:367],
+	251: _Kind_name[367:374],
+	255: _Kind_name[374:381],
 }
 
 func (i Kind) String() string {
@@ -0,0 +1,111 @@
+package sb
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/reusee/e5"
+)
+
+// Lazy holds the tokens of a value and unmarshals them into T on first Get
+type Lazy[T any] struct {
+	state *lazyState[T]
+}
+
+type lazyState[T any] struct {
+	once   sync.Once
+	loaded atomic.Bool
+	ctx    Ctx
+	raw    RawValue
+	value  T
+	err    error
+}
+
+func NewLazy[T any](value T) Lazy[T] {
+	state := &lazyState[T]{
+		value: value,
+	}
+	state.once.Do(func() {})
+	state.loaded.Store(true)
+	return Lazy[T]{
+		state: state,
+	}
+}
+
+func (l Lazy[T]) Get() (ret T, err error) {
+	if l.state == nil {
+		return
+	}
+	l.state.once.Do(func() {
+		l.state.err = Copy(
+			l.state.raw.Iter(),
+			UnmarshalValue(l.state.ctx, reflect.ValueOf(&l.state.value), nil),
+		)
+		l.state.loaded.Store(true)
+	})
+	return l.state.value, l.state.err
+}
+
+// Loaded reports whether the value is unmarshaled
+func (l Lazy[T]) Loaded() bool {
+	return l.state == nil || l.state.loaded.Load()
+}
+
+var _ SBMarshaler = Lazy[int]{}
+
+func (l Lazy[T]) MarshalSB(ctx Ctx, cont Proc) Proc {
+	if l.state == nil {
+		var value T
+		return ctx.Marshal(ctx, reflect.ValueOf(&value).Elem(), cont)
+	}
+	if !l.state.loaded.Load() {
+		// not accessed, emit original tokens
+		return l.state.raw.MarshalSB(ctx, cont)
+	}
+	// wait for concurrent Get
+	value, err := l.Get()
+	if err != nil {
+		return func(token *Token) (Proc, error) {
+			return nil, we.With(e5.With(MarshalError), WithPath(ctx))(err)
+		}
+	}
+	return ctx.Marshal(ctx, reflect.ValueOf(&value).Elem(), cont)
+}
+
+var _ SBUnmarshaler = new(Lazy[int])
+
+var _ tokenCapturer = new(Lazy[int])
+
+func (*Lazy[T]) captureTokens() {}
+
+func (l *Lazy[T]) UnmarshalSB(ctx Ctx, cont Sink) Sink {
+	return captureValue(ctx, func(tokens Tokens) {
+		l.state = &lazyState[T]{
+			ctx: lazyCtx(ctx),
+			raw: RawValue(tokens),
+		}
+	}, cont)
+}
+
+// lazyCtx returns a Ctx with the options of ctx and a copy of its Path for errors
+// states of the enclosing unmarshaling, like the pointer table, are not kept
+func lazyCtx(ctx Ctx) Ctx {
+	if ctx.opts().invalidValues != nil {
+		// validation errors are reported by Get
+		ctx = ctx.withOptions(func(options *ctxOptions) {
+			options.invalidValues = nil
+		})
+	}
+	return Ctx{
+		Marshal:                     ctx.Marshal,
+		Unmarshal:                   ctx.Unmarshal,
+		Path:                        append(ctx.Path[:0:0], ctx.Path...),
+		SkipEmptyStructFields:       ctx.SkipEmptyStructFields,
+		DisallowUnknownStructFields: ctx.DisallowUnknownStructFields,
+		detectCycleEnabled:          ctx.detectCycleEnabled,
+		IgnoreFuncs:                 ctx.IgnoreFuncs,
+		SharedPointers:              ctx.SharedPointers,
+		options:                     ctx.options,
+	}
+}
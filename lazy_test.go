@@ -0,0 +1,156 @@
+package sb
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestLazy(t *testing.T) {
+	type Big struct {
+		Foo int
+		Bar []string
+	}
+	type Cached struct {
+		ID  int
+		Big Lazy[Big]
+	}
+
+	// unmarshal without decoding
+	var c Cached
+	if err := Copy(
+		Marshal(Cached{
+			ID: 1,
+			Big: NewLazy(Big{
+				Foo: 42,
+				Bar: []string{"a", "b"},
+			}),
+		}),
+		Unmarshal(&c),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != 1 {
+		t.Fatal()
+	}
+	if c.Big.Loaded() {
+		t.Fatal()
+	}
+
+	// re-emit original tokens
+	res, err := Compare(
+		Marshal(c),
+		Marshal(Cached{
+			ID: 1,
+			Big: NewLazy(Big{
+				Foo: 42,
+				Bar: []string{"a", "b"},
+			}),
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != 0 {
+		t.Fatal()
+	}
+	if c.Big.Loaded() {
+		t.Fatal()
+	}
+
+	// concurrent get
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			big, err := c.Big.Get()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if big.Foo != 42 || len(big.Bar) != 2 {
+				t.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	if !c.Big.Loaded() {
+		t.Fatal()
+	}
+
+	// marshal loaded value
+	var c2 Cached
+	if err := Copy(Marshal(c), Unmarshal(&c2)); err != nil {
+		t.Fatal(err)
+	}
+	big, err := c2.Big.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if big.Foo != 42 {
+		t.Fatal()
+	}
+
+	// error reported on get
+	if err := Copy(
+		Tokens{
+			{Kind: KindObject},
+			{Kind: KindString, Value: "Big"},
+			{Kind: KindString, Value: "foo"},
+			{Kind: KindObjectEnd},
+		}.Iter(),
+		Unmarshal(&c),
+	); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Big.Get()
+	if !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+	var path Path
+	if !as(err, &path) || path.String() != "/Big" {
+		t.Fatalf("got %v", path)
+	}
+	if err := Copy(Marshal(c), Discard); !is(err, MarshalError) || !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+
+	// zero value
+	var zero Lazy[Big]
+	if _, err := zero.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if res := MustCompare(Marshal(zero), Marshal(Big{})); res != 0 {
+		t.Fatal()
+	}
+
+	// incomplete
+	err = Copy(
+		Tokens{
+			{Kind: KindArray},
+		}.Iter(),
+		Unmarshal(&zero),
+	)
+	if !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+
+	// states of the enclosing unmarshaling are not kept
+	c = Cached{}
+	ctx := DefaultCtx.SharePointers().LimitCollections(10, 10)
+	if err := Copy(
+		Marshal(Cached{
+			Big: NewLazy(Big{}),
+		}),
+		UnmarshalValue(ctx, reflect.ValueOf(&c), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if c.Big.state.ctx.pointers != nil {
+		t.Fatal()
+	}
+	if !c.Big.state.ctx.SharedPointers || c.Big.state.ctx.opts().MaxSliceLength != 10 {
+		t.Fatal()
+	}
+}
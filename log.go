@@ -0,0 +1,362 @@
+package sb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/reusee/e5"
+)
+
+type LogOption interface {
+	IsLogOption()
+}
+
+type LogSegmentSize int64
+
+func (LogSegmentSize) IsLogOption() {}
+
+// record frame: 4 bytes payload length, 4 bytes crc32c of payload, encoded payload
+const logFrameHeaderLen = 8
+
+var logCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+type Log struct {
+	mu          sync.Mutex
+	dir         string
+	segmentSize int64
+	segments    []*logSegment
+	nextSeq     int64
+	closed      bool
+}
+
+type logSegment struct {
+	firstSeq int64
+	count    int64
+	size     int64
+	file     *os.File
+	index    *os.File
+}
+
+func OpenLog(dir string, options ...LogOption) (*Log, error) {
+	l := &Log{
+		dir:         dir,
+		segmentSize: 64 * 1024 * 1024,
+	}
+	for _, option := range options {
+		switch option := option.(type) {
+		case LogSegmentSize:
+			l.segmentSize = int64(option)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil { // NOCOVER
+		return nil, err
+	}
+	var firstSeqs []int64
+	for _, path := range paths {
+		seq, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(path), ".log"), 10, 64)
+		if err != nil {
+			continue
+		}
+		firstSeqs = append(firstSeqs, seq)
+	}
+	sort.Slice(firstSeqs, func(i, j int) bool {
+		return firstSeqs[i] < firstSeqs[j]
+	})
+
+	for i, firstSeq := range firstSeqs {
+		segment, err := l.openSegment(firstSeq, i == len(firstSeqs)-1)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		if i > 0 {
+			prev := l.segments[len(l.segments)-1]
+			if prev.firstSeq+prev.count != firstSeq {
+				l.Close()
+				return nil, we.With(e5.Info("segment %d not contiguous", firstSeq))(LogCorrupted)
+			}
+		}
+		l.segments = append(l.segments, segment)
+	}
+
+	if len(l.segments) == 0 {
+		segment, err := l.openSegment(0, true)
+		if err != nil {
+			return nil, err
+		}
+		l.segments = append(l.segments, segment)
+	}
+
+	last := l.segments[len(l.segments)-1]
+	l.nextSeq = last.firstSeq + last.count
+
+	return l, nil
+}
+
+func (l *Log) segmentPath(firstSeq int64, ext string) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%020d%s", firstSeq, ext))
+}
+
+func (l *Log) openSegment(firstSeq int64, scan bool) (*logSegment, error) {
+	file, err := os.OpenFile(l.segmentPath(firstSeq, ".log"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.OpenFile(l.segmentPath(firstSeq, ".idx"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	segment := &logSegment{
+		firstSeq: firstSeq,
+		file:     file,
+		index:    index,
+	}
+
+	if !scan {
+		info, err := file.Stat()
+		if err != nil { // NOCOVER
+			segment.close()
+			return nil, err
+		}
+		segment.size = info.Size()
+		info, err = index.Stat()
+		if err != nil { // NOCOVER
+			segment.close()
+			return nil, err
+		}
+		segment.count = info.Size() / 8
+		return segment, nil
+	}
+
+	// scan records, truncate partially written or corrupted tail, and rebuild index
+	info, err := file.Stat()
+	if err != nil { // NOCOVER
+		segment.close()
+		return nil, err
+	}
+	var offsets []byte
+	var offset int64
+	header := make([]byte, logFrameHeaderLen)
+	for {
+		payload, err := readLogFrame(file, offset, info.Size(), header)
+		if err != nil {
+			break
+		}
+		offsets = binary.LittleEndian.AppendUint64(offsets, uint64(offset))
+		offset += logFrameHeaderLen + int64(len(payload))
+	}
+	if err := file.Truncate(offset); err != nil {
+		segment.close()
+		return nil, err
+	}
+	if err := index.Truncate(0); err != nil {
+		segment.close()
+		return nil, err
+	}
+	if _, err := index.WriteAt(offsets, 0); err != nil {
+		segment.close()
+		return nil, err
+	}
+	segment.size = offset
+	segment.count = int64(len(offsets) / 8)
+
+	return segment, nil
+}
+
+func readLogFrame(r io.ReaderAt, offset int64, size int64, header []byte) ([]byte, error) {
+	if _, err := r.ReadAt(header, offset); err != nil {
+		return nil, we.With(e5.With(LogCorrupted), e5.With(Offset(offset)))(err)
+	}
+	length := binary.LittleEndian.Uint32(header[:4])
+	sum := binary.LittleEndian.Uint32(header[4:8])
+	if offset+logFrameHeaderLen+int64(length) > size {
+		return nil, we.With(e5.With(Offset(offset)), e5.With(io.ErrUnexpectedEOF))(LogCorrupted)
+	}
+	payload := make([]byte, length)
+	if _, err := r.ReadAt(payload, offset+logFrameHeaderLen); err != nil {
+		return nil, we.With(e5.With(LogCorrupted), e5.With(Offset(offset)))(err)
+	}
+	if crc32.Checksum(payload, logCRCTable) != sum {
+		return nil, we.With(e5.With(Offset(offset)), e5.Info("checksum mismatch"))(LogCorrupted)
+	}
+	return payload, nil
+}
+
+func (s *logSegment) close() error {
+	return errors.Join(s.file.Close(), s.index.Close())
+}
+
+// sealSegment syncs a full segment and reopens it read-only
+// segments other than the last are not synced by Sync
+func (l *Log) sealSegment(s *logSegment) error {
+	if err := errors.Join(s.file.Sync(), s.index.Sync()); err != nil {
+		return err
+	}
+	if err := s.close(); err != nil {
+		return err
+	}
+	file, err := os.Open(l.segmentPath(s.firstSeq, ".log"))
+	if err != nil {
+		return err
+	}
+	index, err := os.Open(l.segmentPath(s.firstSeq, ".idx"))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file = file
+	s.index = index
+	return nil
+}
+
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	return errors.Join(f.Sync(), f.Close())
+}
+
+func (l *Log) Append(value Stream) (seq int64, err error) {
+	buf := new(bytes.Buffer)
+	buf.Write(make([]byte, logFrameHeaderLen))
+	if err := Copy(value, Encode(buf)); err != nil {
+		return 0, err
+	}
+	frame := buf.Bytes()
+	payload := frame[logFrameHeaderLen:]
+	if int64(len(payload)) > math.MaxUint32 {
+		return 0, we.With(e5.Info("payload length: %d", len(payload)))(LogRecordTooLarge)
+	}
+	binary.LittleEndian.PutUint32(frame[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.Checksum(payload, logCRCTable))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return 0, LogClosed
+	}
+
+	segment := l.segments[len(l.segments)-1]
+	if segment.size > 0 && segment.size+int64(len(frame)) > l.segmentSize {
+		// rotate
+		if err := l.sealSegment(segment); err != nil {
+			return 0, err
+		}
+		segment, err = l.openSegment(l.nextSeq, true)
+		if err != nil {
+			return 0, err
+		}
+		l.segments = append(l.segments, segment)
+		if err := syncDir(l.dir); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := segment.file.WriteAt(frame, segment.size); err != nil {
+		return 0, err
+	}
+	var offset [8]byte
+	binary.LittleEndian.PutUint64(offset[:], uint64(segment.size))
+	if _, err := segment.index.WriteAt(offset[:], segment.count*8); err != nil {
+		return 0, err
+	}
+	segment.size += int64(len(frame))
+	segment.count++
+	seq = l.nextSeq
+	l.nextSeq++
+
+	return seq, nil
+}
+
+func (l *Log) Len() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.nextSeq
+}
+
+func (l *Log) ReadAt(seq int64) (Stream, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, LogClosed
+	}
+	if seq < 0 || seq >= l.nextSeq {
+		return nil, we.With(e5.Info("seq: %d", seq))(NotFound)
+	}
+	i := sort.Search(len(l.segments), func(i int) bool {
+		return l.segments[i].firstSeq > seq
+	}) - 1
+	segment := l.segments[i]
+	var buf [8]byte
+	if _, err := segment.index.ReadAt(buf[:], (seq-segment.firstSeq)*8); err != nil {
+		return nil, we.With(e5.With(LogCorrupted))(err)
+	}
+	payload, err := readLogFrame(
+		segment.file,
+		int64(binary.LittleEndian.Uint64(buf[:])),
+		segment.size,
+		make([]byte, logFrameHeaderLen),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(bytes.NewReader(payload)), nil
+}
+
+func (l *Log) Iter(from int64, fn func(seq int64, value Stream) error) error {
+	end := l.Len()
+	for seq := from; seq < end; seq++ {
+		value, err := l.ReadAt(seq)
+		if err != nil {
+			return err
+		}
+		if err := fn(seq, value); is(err, Break) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return LogClosed
+	}
+	segment := l.segments[len(l.segments)-1]
+	return errors.Join(segment.file.Sync(), segment.index.Sync())
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	var errs []error
+	for _, segment := range l.segments {
+		errs = append(errs, segment.close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,153 @@
+package sb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	dir := t.TempDir()
+	log, err := OpenLog(dir, LogSegmentSize(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		seq, err := log.Append(Marshal(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq != int64(i) {
+			t.Fatal()
+		}
+	}
+	if len(log.segments) < 2 {
+		t.Fatal("should rotate")
+	}
+	// full segments are read-only
+	if _, err := log.segments[0].file.WriteAt([]byte{0}, 0); err == nil {
+		t.Fatal()
+	}
+
+	check := func(log *Log, n int) {
+		t.Helper()
+		if log.Len() != int64(n) {
+			t.Fatalf("got %d", log.Len())
+		}
+		for i := 0; i < n; i++ {
+			value, err := log.ReadAt(int64(i))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var v int
+			if err := Copy(value, Unmarshal(&v)); err != nil {
+				t.Fatal(err)
+			}
+			if v != i {
+				t.Fatal()
+			}
+		}
+		if _, err := log.ReadAt(int64(n)); !is(err, NotFound) {
+			t.Fatal()
+		}
+		var seqs []int64
+		if err := log.Iter(10, func(seq int64, value Stream) error {
+			var v int
+			if err := Copy(value, Unmarshal(&v)); err != nil {
+				return err
+			}
+			if int64(v) != seq {
+				t.Fatal()
+			}
+			seqs = append(seqs, seq)
+			if len(seqs) == 5 {
+				return Break
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if len(seqs) != 5 || seqs[0] != 10 {
+			t.Fatal()
+		}
+	}
+	check(log, 100)
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := log.Append(Marshal(1)); !is(err, LogClosed) {
+		t.Fatal()
+	}
+
+	// reopen
+	log, err = OpenLog(dir, LogSegmentSize(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(log, 100)
+	last := log.segments[len(log.segments)-1]
+	lastPath := last.file.Name()
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// torn write
+	f, err := os.OpenFile(lastPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{42, 0, 0, 0, 1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	log, err = OpenLog(dir, LogSegmentSize(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(log, 100)
+	if seq, err := log.Append(Marshal(100)); err != nil {
+		t.Fatal(err)
+	} else if seq != 100 {
+		t.Fatal()
+	}
+	check(log, 101)
+	last = log.segments[len(log.segments)-1]
+	lastSize := last.size
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupted last record
+	f, err = os.OpenFile(lastPath, os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte{0xff}, lastSize-1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	log, err = OpenLog(dir, LogSegmentSize(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(log, 100)
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// missing segment
+	paths, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(paths[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenLog(dir, LogSegmentSize(256)); !is(err, LogCorrupted) {
+		t.Fatal()
+	}
+}
@@ -0,0 +1,124 @@
+package sb
+
+import (
+	"cmp"
+	"encoding"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/reusee/e5"
+)
+
+var (
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+var nativeSortKeyTypes sync.Map
+
+// hasNativeKeySort reports whether keys of type t can be sorted without marshaling
+// t must be a scalar type marshaled by the built-in rules, so that native order is the same as Compare
+func hasNativeKeySort(t reflect.Type) bool {
+	if v, ok := nativeSortKeyTypes.Load(t); ok {
+		return v.(bool)
+	}
+	ret := false
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		ret = !t.Implements(sbMarshalerType) &&
+			!t.Implements(binaryMarshalerType) &&
+			!t.Implements(textMarshalerType) &&
+			!isNormalizer(t)
+	}
+	nativeSortKeyTypes.Store(t, ret)
+	return ret
+}
+
+func marshalMapNativeSort(ctx Ctx, value reflect.Value, cont Proc) Proc {
+	return func(token *Token) (Proc, error) {
+		tuples := make([]*MapTuple, 0, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			tuples = append(tuples, &MapTuple{
+				Key:   iter.Key(),
+				Value: iter.Value(),
+			})
+		}
+
+		switch value.Type().Key().Kind() {
+		case reflect.String:
+			sortMapTuples(tuples, reflect.Value.String)
+		case reflect.Bool:
+			sortMapTuples(tuples, func(v reflect.Value) int {
+				if v.Bool() {
+					return 1
+				}
+				return 0
+			})
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			sortMapTuples(tuples, reflect.Value.Int)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			sortMapTuples(tuples, reflect.Value.Uint)
+		case reflect.Float32, reflect.Float64:
+			for _, tuple := range tuples {
+				if math.IsNaN(tuple.Key.Float()) {
+					return nil, we.With(WithPath(ctx), e5.With(BadMapKey))(MarshalError)
+				}
+			}
+			// cmp.Compare treats -0 and +0 as equal, like Compare
+			sortMapTuples(tuples, reflect.Value.Float)
+		}
+
+		*token = mapToken
+		return MarshalMapTuples(ctx, tuples, cont), nil
+	}
+}
+
+func sortMapTuples[T cmp.Ordered](tuples []*MapTuple, key func(reflect.Value) T) {
+	type keyed struct {
+		key   T
+		tuple *MapTuple
+	}
+	keys := make([]keyed, 0, len(tuples))
+	for _, tuple := range tuples {
+		keys = append(keys, keyed{
+			key:   key(tuple.Key),
+			tuple: tuple,
+		})
+	}
+	slices.SortStableFunc(keys, func(a, b keyed) int {
+		return cmp.Compare(a.key, b.key)
+	})
+	for i, k := range keys {
+		tuples[i] = k.tuple
+	}
+}
+
+// marshalMapUnsorted marshals entries in map iteration order
+func marshalMapUnsorted(ctx Ctx, value reflect.Value, cont Proc) Proc {
+	return func(token *Token) (Proc, error) {
+		tuples := make([]*MapTuple, 0, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			key := iter.Key()
+			if key.Kind() == reflect.Interface {
+				key = key.Elem()
+			}
+			if (key.Kind() == reflect.Float32 || key.Kind() == reflect.Float64) &&
+				math.IsNaN(key.Float()) {
+				return nil, we.With(WithPath(ctx), e5.With(BadMapKey))(MarshalError)
+			}
+			tuples = append(tuples, &MapTuple{
+				Key:   iter.Key(),
+				Value: iter.Value(),
+			})
+		}
+		*token = mapToken
+		return MarshalMapTuples(ctx, tuples, cont), nil
+	}
+}
@@ -0,0 +1,123 @@
+package sb
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestMapNativeSort(t *testing.T) {
+	type namedString string
+	maps := []any{
+		map[string]int{},
+		map[namedString]int{},
+		map[int]int{},
+		map[int8]int{},
+		map[int64]int{},
+		map[uint]int{},
+		map[uint16]int{},
+		map[uintptr]int{},
+		map[float32]int{},
+		map[float64]int{},
+		map[bool]int{},
+	}
+	for _, m := range maps {
+		value := reflect.ValueOf(m)
+		keyType := value.Type().Key()
+		if !hasNativeKeySort(keyType) {
+			t.Fatalf("%v", keyType)
+		}
+		for i := 0; i < 64; i++ {
+			var key reflect.Value
+			switch keyType.Kind() {
+			case reflect.String:
+				key = reflect.ValueOf(strconv.Itoa(rand.Intn(1000)) + "世\x00")
+			case reflect.Bool:
+				key = reflect.ValueOf(i%2 == 0)
+			case reflect.Float32, reflect.Float64:
+				key = reflect.ValueOf(rand.NormFloat64())
+			case reflect.Uint, reflect.Uint16, reflect.Uintptr:
+				key = reflect.ValueOf(rand.Intn(math.MaxInt16))
+			default:
+				key = reflect.ValueOf(rand.Intn(256) - 128)
+			}
+			value.SetMapIndex(key.Convert(keyType), reflect.ValueOf(i))
+		}
+		if keyType.Kind() == reflect.Float64 {
+			value.SetMapIndex(reflect.ValueOf(math.Copysign(0, -1)), reflect.ValueOf(1))
+			value.SetMapIndex(reflect.ValueOf(math.Inf(-1)), reflect.ValueOf(1))
+			value.SetMapIndex(reflect.ValueOf(math.Inf(1)), reflect.ValueOf(1))
+		}
+
+		iterProc := MarshalMapIter(DefaultCtx, value.MapRange(), nil, nil)
+		if res := MustCompare(Marshal(m), &iterProc); res != 0 {
+			t.Fatalf("%T: order not match", m)
+		}
+	}
+
+	// fallback
+	if hasNativeKeySort(reflect.TypeOf(benchInt(0))) {
+		t.Fatal()
+	}
+	if hasNativeKeySort(reflect.TypeOf((*any)(nil)).Elem()) {
+		t.Fatal()
+	}
+	if MustCompare(
+		Marshal(map[any]int{"b": 1, 1: 2, "a": 3}),
+		Tokens{
+			{Kind: KindMap},
+			{Kind: KindString, Value: "a"},
+			{Kind: KindInt, Value: 3},
+			{Kind: KindString, Value: "b"},
+			{Kind: KindInt, Value: 1},
+			{Kind: KindInt, Value: 1},
+			{Kind: KindInt, Value: 2},
+			{Kind: KindMapEnd},
+		}.Iter(),
+	) != 0 {
+		t.Fatal()
+	}
+
+	// NaN
+	if err := Copy(
+		Marshal(map[float64]int{math.NaN(): 1}),
+		Discard,
+	); !is(err, BadMapKey) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestMapUnsorted(t *testing.T) {
+	m := map[string]int{}
+	for i := 0; i < 64; i++ {
+		m[strconv.Itoa(i)] = i
+	}
+	var m2 map[string]int
+	if err := Copy(
+		MarshalCtx(DefaultCtx.Unsorted(), m),
+		Unmarshal(&m2),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, m2) {
+		t.Fatal()
+	}
+
+	// canonical overrides unsorted
+	if MustCompare(
+		MarshalCtx(DefaultCtx.Unsorted().Canonical(), m),
+		Marshal(m),
+	) != 0 {
+		t.Fatal()
+	}
+
+	// NaN
+	if err := Copy(
+		MarshalCtx(DefaultCtx.Unsorted(), map[any]int{math.NaN(): 1}),
+		Discard,
+	); !is(err, BadMapKey) {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -12,6 +12,8 @@ type SBMarshaler interface {
 	MarshalSB(ctx Ctx, cont Proc) Proc
 }
 
+var sbMarshalerType = reflect.TypeOf((*SBMarshaler)(nil)).Elem()
+
 func Marshal(value any) *Proc {
 	marshaler := MarshalValue(Ctx{
 		Marshal: MarshalValue,
@@ -38,8 +40,65 @@ func MarshalValue(ctx Ctx, value reflect.Value, cont Proc) Proc {
 	if ctx.Marshal == nil {
 		ctx.Marshal = MarshalValue
 	}
+	if ctx.SharedPointers && ctx.pointers == nil {
+		ctx.pointers = newPointerTable()
+	}
+
+	var marshal Proc
+	if value.IsValid() {
+		marshal = marshalHooks(ctx, value, cont)
+	}
+	if marshal == nil {
+		marshal = marshalBuiltin(ctx, value, cont)
+	}
+
+	if value.IsValid() {
+		if name, ok := registeredTypeToName.Load(value.Type()); ok {
+			return func(token *Token) (Proc, error) {
+				token.Kind = KindTypeName
+				token.Value = name.(string)
+				return marshal, nil
+			}
+		}
+	}
+
+	return marshal
+}
+
+// marshalHooks returns the Proc of type codecs or SBNormalizer, nil if not applicable
+func marshalHooks(ctx Ctx, value reflect.Value, cont Proc) Proc {
+	if codecs := ctx.opts().TypeCodecs; codecs.codecs != nil {
+		if codec, ok := codecs.Get(value.Type()); ok && codec.Marshal != nil {
+			return codec.Marshal(ctx, value, cont)
+		}
+		if value.Kind() == reflect.Ptr && !value.IsNil() && ctx.pointers == nil {
+			if codec, ok := codecs.Get(value.Type().Elem()); ok && codec.Marshal != nil {
+				// do not use methods of the pointer type
+				return codec.Marshal(ctx, value.Elem(), cont)
+			}
+		}
+	}
+	if isNormalizer(value.Type()) {
+		return func(token *Token) (Proc, error) {
+			normalized, err := normalizeValue(ctx, value)
+			if err != nil {
+				return nil, we.With(e5.With(MarshalError), WithPath(ctx))(err)
+			}
+			return marshalBuiltin(ctx, normalized, cont)(token)
+		}
+	}
+	return nil
+}
 
-	marshal := func(token *Token) (Proc, error) {
+// marshalBuiltin marshals value by the built-in rules
+func marshalBuiltin(ctx Ctx, value reflect.Value, cont Proc) Proc {
+	return func(token *Token) (Proc, error) {
+
+		if value.Kind() == reflect.Interface {
+			if u, ok := registeredUnions.Load(value.Type()); ok {
+				return marshalUnion(ctx, u.(*union), value, token, cont)
+			}
+		}
 
 		if value.IsValid() {
 
@@ -170,6 +229,8 @@ func MarshalValue(ctx Ctx, value reflect.Value, cont Proc) Proc {
 			if value.IsNil() {
 				*token = Nil
 				return cont, nil
+			} else if ctx.pointers != nil && value.Kind() == reflect.Ptr {
+				return marshalPointer(ctx, value, token, cont), nil
 			} else {
 				ctx.pointerDepth++
 				if ctx.pointerDepth == 1000 {
@@ -318,18 +379,6 @@ func MarshalValue(ctx Ctx, value reflect.Value, cont Proc) Proc {
 
 		}
 	}
-
-	if value.IsValid() {
-		if name, ok := registeredTypeToName.Load(value.Type()); ok {
-			return func(token *Token) (Proc, error) {
-				token.Kind = KindTypeName
-				token.Value = name.(string)
-				return marshal, nil
-			}
-		}
-	}
-
-	return marshal
 }
 
 var arrayEndToken = reflect.ValueOf(&Token{
@@ -380,50 +429,52 @@ func MarshalStruct(ctx Ctx, value reflect.Value, cont Proc) Proc {
 }
 
 func MarshalStructFields(ctx Ctx, value reflect.Value, cont Proc) Proc {
-	valueType := value.Type()
-	numField := valueType.NumField()
+	fieldSet := getStructFields(value.Type())
+	fields := fieldSet.Fields
 	fieldIdx := 0
 	var proc Proc
 	proc = func(_ *Token) (Proc, error) {
-		if fieldIdx == numField {
-			return ctx.Marshal(
+		if fieldIdx == len(fields) {
+			end := ctx.Marshal(
 				ctx,
 				objectEndToken,
 				cont,
-			), nil
+			)
+			if fieldSet.UnknownFields != nil {
+				if v, err := value.FieldByIndexErr(fieldSet.UnknownFields); err == nil && v.Len() > 0 {
+					return marshalUnknownFields(v.Interface().(UnknownFields), end), nil
+				}
+			}
+			return end, nil
 		}
 
-		field := valueType.Field(fieldIdx)
+		field := fields[fieldIdx]
+		fieldIdx++
+		fieldValue, err := value.FieldByIndexErr(field.Index)
+		if err != nil {
+			// nil embedded pointer
+			return proc, nil
+		}
 		if ctx.SkipEmptyStructFields {
-			fieldValue := value.Field(fieldIdx)
 			if fieldValue.IsZero() {
-				fieldIdx++
 				return proc, nil
 			}
 			if field.Type.Kind() == reflect.Slice && fieldValue.Len() == 0 {
-				fieldIdx++
 				return proc, nil
 			}
 		}
-		if field.PkgPath != "" {
-			// unexported field
-			fieldIdx++
-			return proc, nil
-		}
 
 		if ctx.IgnoreFuncs && field.Type.Kind() == reflect.Func {
-			fieldIdx++
 			return proc, nil
 		}
 
-		fieldIdx++
 		return ctx.Marshal(
 			ctx.WithPath(field.Name),
 			reflect.ValueOf(field.Name),
 			func(token *Token) (Proc, error) {
 				return ctx.Marshal(
 					ctx.WithPath(field.Name),
-					value.FieldByIndex(field.Index),
+					fieldValue,
 					proc,
 				), nil
 			},
@@ -439,6 +490,12 @@ type MapTuple struct {
 }
 
 func MarshalMap(ctx Ctx, value reflect.Value, cont Proc) Proc {
+	if ctx.opts().UnsortedMaps && !ctx.opts().CanonicalMaps {
+		return marshalMapUnsorted(ctx, value, cont)
+	}
+	if hasNativeKeySort(value.Type().Key()) {
+		return marshalMapNativeSort(ctx, value, cont)
+	}
 	return MarshalMapIter(
 		ctx,
 		value.MapRange(),
@@ -2,6 +2,7 @@ package sb
 
 import (
 	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -91,6 +92,56 @@ func BenchmarkMarshalMap(b *testing.B) {
 	}
 }
 
+func benchStringMap() map[string]int {
+	m := make(map[string]int)
+	for i := 0; i < 64; i++ {
+		m[strconv.Itoa(i)] = i
+	}
+	return m
+}
+
+func BenchmarkMarshalStringMap(b *testing.B) {
+	m := benchStringMap()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Copy(
+			Marshal(m),
+			Discard,
+		); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalStringMapIter(b *testing.B) {
+	m := benchStringMap()
+	value := reflect.ValueOf(m)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		proc := MarshalMapIter(DefaultCtx, value.MapRange(), nil, nil)
+		if err := Copy(
+			&proc,
+			Discard,
+		); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalStringMapUnsorted(b *testing.B) {
+	m := benchStringMap()
+	ctx := DefaultCtx.Unsorted()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Copy(
+			MarshalCtx(ctx, m),
+			Discard,
+		); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkMarshalTuple(b *testing.B) {
 	tuple := func() (int, int) {
 		return 42, 1
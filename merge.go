@@ -0,0 +1,48 @@
+package sb
+
+import "reflect"
+
+// MergeMode controls how unmarshaling treats existing values in the target
+type MergeMode uint8
+
+const (
+	// fields absent from the stream are kept, map entries are set, slices are appended
+	MergeDefault MergeMode = iota
+	// like MergeDefault, but map entries and pointed values are merged recursively, slices follow SliceMergePolicy
+	MergePatch
+	// clear the target before unmarshaling
+	MergeReplace
+)
+
+type SliceMergePolicy uint8
+
+const (
+	SliceAppend SliceMergePolicy = iota
+	SliceReplace
+)
+
+func (c Ctx) Patch(slicePolicy SliceMergePolicy) Ctx {
+	return c.withOptions(func(options *ctxOptions) {
+		options.Merge = MergePatch
+		options.SliceMerge = slicePolicy
+	})
+}
+
+func (c Ctx) Replace() Ctx {
+	return c.withOptions(func(options *ctxOptions) {
+		options.Merge = MergeReplace
+	})
+}
+
+func unmarshalReplace(ctx Ctx, target reflect.Value, cont Sink) Sink {
+	// nested values are already cleared
+	ctx = ctx.withOptions(func(options *ctxOptions) {
+		options.Merge = MergeDefault
+	})
+	return func(token *Token) (Sink, error) {
+		if token.Valid() {
+			target.Elem().SetZero()
+		}
+		return ctx.Unmarshal(ctx, target, cont)(token)
+	}
+}
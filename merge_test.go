@@ -0,0 +1,142 @@
+package sb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	type Server struct {
+		Host string
+		Port int
+	}
+	type Config struct {
+		Name    string
+		Tags    []string
+		Servers map[string]Server
+		Limits  map[string]int
+		Primary *Server
+	}
+
+	newConfig := func() Config {
+		return Config{
+			Name: "foo",
+			Tags: []string{"a"},
+			Servers: map[string]Server{
+				"x": {Host: "x.local", Port: 1},
+				"y": {Host: "y.local", Port: 2},
+			},
+			Limits: map[string]int{
+				"cpu": 1,
+			},
+			Primary: &Server{
+				Host: "p.local",
+				Port: 3,
+			},
+		}
+	}
+
+	patch := Tokens{
+		{Kind: KindObject},
+		{Kind: KindString, Value: "Tags"},
+		{Kind: KindArray},
+		{Kind: KindString, Value: "b"},
+		{Kind: KindArrayEnd},
+		{Kind: KindString, Value: "Servers"},
+		{Kind: KindMap},
+		{Kind: KindString, Value: "x"},
+		{Kind: KindObject},
+		{Kind: KindString, Value: "Port"},
+		{Kind: KindInt, Value: 10},
+		{Kind: KindObjectEnd},
+		{Kind: KindMapEnd},
+		{Kind: KindString, Value: "Primary"},
+		{Kind: KindObject},
+		{Kind: KindString, Value: "Port"},
+		{Kind: KindInt, Value: 30},
+		{Kind: KindObjectEnd},
+		{Kind: KindObjectEnd},
+	}
+
+	unmarshal := func(ctx Ctx, target *Config) {
+		t.Helper()
+		if err := Copy(
+			patch.Iter(),
+			UnmarshalValue(ctx, reflect.ValueOf(target), nil),
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// default
+	c := newConfig()
+	unmarshal(DefaultCtx, &c)
+	if c.Servers["x"] != (Server{Port: 10}) {
+		t.Fatalf("got %+v", c.Servers["x"])
+	}
+	if *c.Primary != (Server{Port: 30}) {
+		t.Fatalf("got %+v", c.Primary)
+	}
+
+	// patch, append slices
+	c = newConfig()
+	primary := c.Primary
+	unmarshal(DefaultCtx.Patch(SliceAppend), &c)
+	expected := newConfig()
+	expected.Tags = []string{"a", "b"}
+	expected.Servers["x"] = Server{Host: "x.local", Port: 10}
+	expected.Primary.Port = 30
+	if !reflect.DeepEqual(c, expected) {
+		t.Fatalf("got %+v", c)
+	}
+	if c.Primary != primary {
+		t.Fatal()
+	}
+
+	// patch, replace slices
+	c = newConfig()
+	unmarshal(DefaultCtx.Patch(SliceReplace), &c)
+	expected.Tags = []string{"b"}
+	if !reflect.DeepEqual(c, expected) {
+		t.Fatalf("got %+v", c)
+	}
+
+	// replace
+	c = newConfig()
+	unmarshal(DefaultCtx.Replace(), &c)
+	if !reflect.DeepEqual(c, Config{
+		Tags: []string{"b"},
+		Servers: map[string]Server{
+			"x": {Port: 10},
+		},
+		Primary: &Server{
+			Port: 30,
+		},
+	}) {
+		t.Fatalf("got %+v", c)
+	}
+
+	// replace with nil
+	c = newConfig()
+	if err := Copy(
+		Marshal(nil),
+		UnmarshalValue(DefaultCtx.Replace(), reflect.ValueOf(&c), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, Config{}) {
+		t.Fatalf("got %+v", c)
+	}
+
+	// atomic patch
+	c = newConfig()
+	if err := Copy(
+		patch[:len(patch)-1].Iter(),
+		UnmarshalValue(DefaultCtx.Patch(SliceAppend).Atomic(), reflect.ValueOf(&c), nil),
+	); !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+	if !reflect.DeepEqual(c, newConfig()) {
+		t.Fatalf("got %+v", c)
+	}
+}
@@ -0,0 +1,161 @@
+package sb
+
+import (
+	"io"
+	"reflect"
+	"slices"
+
+	"github.com/reusee/e5"
+)
+
+// OrderedMap is a map that marshals entries in insertion order
+// use Ctx.Canonical to marshal entries sorted by keys like builtin maps, for hashing or comparing
+type OrderedMap[K comparable, V any] struct {
+	entries []orderedMapEntry[K, V]
+	index   map[K]int
+}
+
+type orderedMapEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.entries)
+}
+
+func (m *OrderedMap[K, V]) Get(key K) (value V, ok bool) {
+	i, ok := m.index[key]
+	if !ok {
+		return
+	}
+	return m.entries[i].Value, true
+}
+
+// Set sets the value of key. new keys are appended, existing keys keep their positions
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	if i, ok := m.index[key]; ok {
+		m.entries[i].Value = value
+		return
+	}
+	if m.index == nil {
+		m.index = make(map[K]int)
+	}
+	m.index[key] = len(m.entries)
+	m.entries = append(m.entries, orderedMapEntry[K, V]{
+		Key:   key,
+		Value: value,
+	})
+}
+
+func (m *OrderedMap[K, V]) Delete(key K) {
+	i, ok := m.index[key]
+	if !ok {
+		return
+	}
+	delete(m.index, key)
+	m.entries = slices.Delete(m.entries, i, i+1)
+	for ; i < len(m.entries); i++ {
+		m.index[m.entries[i].Key] = i
+	}
+}
+
+// Range calls fn for each entry in order, until fn returns false
+func (m *OrderedMap[K, V]) Range(fn func(key K, value V) bool) {
+	for _, entry := range m.entries {
+		if !fn(entry.Key, entry.Value) {
+			break
+		}
+	}
+}
+
+var _ SBMarshaler = OrderedMap[int, int]{}
+
+func (m OrderedMap[K, V]) MarshalSB(ctx Ctx, cont Proc) Proc {
+	tuples := make([]*MapTuple, 0, len(m.entries))
+	for i := range m.entries {
+		entry := &m.entries[i]
+		tuples = append(tuples, &MapTuple{
+			Key:   reflect.ValueOf(&entry.Key).Elem(),
+			Value: reflect.ValueOf(&entry.Value).Elem(),
+		})
+	}
+	return func(token *Token) (Proc, error) {
+		if ctx.opts().CanonicalMaps {
+			for _, tuple := range tuples {
+				var err error
+				// tokens are for sorting only, so do not call ctx.Marshal
+				keyMarshalProc := MarshalValue(Ctx{}, tuple.Key, nil)
+				tuple.KeyTokens, err = TokensFromStream(&keyMarshalProc)
+				if err != nil {
+					return nil, we.With(e5.With(MarshalError), WithPath(ctx))(err)
+				}
+			}
+			slices.SortFunc(tuples, func(a, b *MapTuple) int {
+				return MustCompare(
+					a.KeyTokens.Iter(),
+					b.KeyTokens.Iter(),
+				)
+			})
+		}
+		*token = mapToken
+		return MarshalMapTuples(ctx, tuples, cont), nil
+	}
+}
+
+var _ SBUnmarshaler = new(OrderedMap[int, int])
+
+// UnmarshalSB accepts KindMap and KindObject, entries are set in stream order
+func (m *OrderedMap[K, V]) UnmarshalSB(ctx Ctx, cont Sink) Sink {
+	return func(token *Token) (Sink, error) {
+		if token.Invalid() {
+			return nil, we.With(WithPath(ctx), io.ErrUnexpectedEOF)(UnmarshalError)
+		}
+		var endKind Kind
+		switch token.Kind {
+		case KindNil:
+			return cont, nil
+		case KindMap:
+			endKind = KindMapEnd
+		case KindObject:
+			endKind = KindObjectEnd
+		default:
+			return nil, we.With(
+				WithPath(ctx),
+				e5.Info("expecting %s or %s, got %s", KindMap, KindObject, token.Kind),
+			)(UnmarshalError)
+		}
+
+		var n int
+		var sink Sink
+		sink = func(token *Token) (Sink, error) {
+			if token.Invalid() {
+				return nil, we.With(WithPath(ctx), io.ErrUnexpectedEOF)(UnmarshalError)
+			}
+			if token.Kind == endKind {
+				return cont, nil
+			}
+			if ctx.opts().MaxMapLength > 0 && n >= ctx.opts().MaxMapLength {
+				return nil, we.With(WithPath(ctx), MapTooLarge)(UnmarshalError)
+			}
+			n++
+			var key K
+			return ctx.Unmarshal(
+				ctx,
+				reflect.ValueOf(&key),
+				func(token *Token) (Sink, error) {
+					var value V
+					return ctx.Unmarshal(
+						ctx.WithPath(key),
+						reflect.ValueOf(&value),
+						func(token *Token) (Sink, error) {
+							m.Set(key, value)
+							return sink(token)
+						},
+					)(token)
+				},
+			)(token)
+		}
+		return sink, nil
+	}
+}
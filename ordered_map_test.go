@@ -0,0 +1,136 @@
+package sb
+
+import (
+	"crypto/sha256"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+	var m OrderedMap[string, int]
+	m.Set("c", 1)
+	m.Set("a", 2)
+	m.Set("b", 3)
+	m.Set("a", 4)
+	if m.Len() != 3 {
+		t.Fatal()
+	}
+	if v, ok := m.Get("a"); !ok || v != 4 {
+		t.Fatal()
+	}
+	if _, ok := m.Get("d"); ok {
+		t.Fatal()
+	}
+
+	keys := func(m *OrderedMap[string, int]) (ret []string) {
+		m.Range(func(key string, _ int) bool {
+			ret = append(ret, key)
+			return true
+		})
+		return
+	}
+	if !slices.Equal(keys(&m), []string{"c", "a", "b"}) {
+		t.Fatal()
+	}
+
+	// marshal in insertion order
+	tokens, err := TokensFromStream(Marshal(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[0].Kind != KindMap || tokens[1].Value != "c" || tokens[len(tokens)-1].Kind != KindMapEnd {
+		t.Fatalf("got %+v", tokens)
+	}
+
+	// unmarshal in stream order
+	var m2 OrderedMap[string, int]
+	if err := Copy(tokens.Iter(), Unmarshal(&m2)); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys(&m2), []string{"c", "a", "b"}) {
+		t.Fatal()
+	}
+
+	// from json object
+	var m3 OrderedMap[string, int]
+	if err := Copy(
+		DecodeJson(strings.NewReader(`{"z": 1, "y": 2}`), nil),
+		Unmarshal(&m3),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys(&m3), []string{"z", "y"}) {
+		t.Fatal()
+	}
+
+	// canonical
+	if MustCompare(
+		MarshalCtx(DefaultCtx.Canonical(), m),
+		Marshal(map[string]int{
+			"a": 4,
+			"b": 3,
+			"c": 1,
+		}),
+	) != 0 {
+		t.Fatal()
+	}
+	type Doc struct {
+		M OrderedMap[string, int]
+	}
+	hash := func(m OrderedMap[string, int]) []byte {
+		var sum []byte
+		if err := Copy(
+			MarshalCtx(DefaultCtx.Canonical(), Doc{M: m}),
+			Hash(sha256.New, &sum, nil),
+		); err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+	var reordered OrderedMap[string, int]
+	reordered.Set("a", 4)
+	reordered.Set("b", 3)
+	reordered.Set("c", 1)
+	if !slices.Equal(hash(m), hash(reordered)) {
+		t.Fatal()
+	}
+
+	// delete
+	m.Delete("c")
+	m.Delete("d")
+	if !slices.Equal(keys(&m), []string{"a", "b"}) {
+		t.Fatal()
+	}
+	if v, ok := m.Get("b"); !ok || v != 3 {
+		t.Fatal()
+	}
+
+	// range break
+	var n int
+	m.Range(func(string, int) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Fatal()
+	}
+
+	// errors
+	if err := Copy(Marshal(42), Unmarshal(&m2)); !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+	if err := Copy(
+		Tokens{{Kind: KindMap}}.Iter(),
+		Unmarshal(&m2),
+	); !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+	if err := Copy(
+		Marshal(map[string]int{"a": 1, "b": 2}),
+		UnmarshalValue(DefaultCtx.LimitCollections(0, 1), reflect.ValueOf(&m2), nil),
+	); !is(err, MapTooLarge) {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package sb
+
+type pathFrame struct {
+	Kind Kind
+	N    int
+	Key  any
+}
+
+// pathTracker tracks the path of the next token in a stream
+type pathTracker struct {
+	frames []pathFrame
+}
+
+func (p *pathTracker) Path() Path {
+	var path Path
+	for _, frame := range p.frames {
+		switch frame.Kind {
+		case KindArray, KindTuple:
+			path = append(path, frame.N)
+		case KindObject, KindMap:
+			if frame.N%2 == 1 {
+				path = append(path, frame.Key)
+			}
+		}
+	}
+	return path
+}
+
+// AtKey reports whether the next token is a key of object or map
+func (p *pathTracker) AtKey() bool {
+	if len(p.frames) == 0 {
+		return false
+	}
+	top := p.frames[len(p.frames)-1]
+	return (top.Kind == KindObject || top.Kind == KindMap) && top.N%2 == 0
+}
+
+func (p *pathTracker) Push(token *Token) {
+	value := token.Value
+	switch token.Kind {
+	case KindArray, KindObject, KindMap, KindTuple, KindTypeName, KindPointerID:
+		p.frames = append(p.frames, pathFrame{
+			Kind: token.Kind,
+		})
+		return
+	case KindStringBegin, KindBytesBegin:
+		// segments are one value
+		frame := pathFrame{
+			Kind: token.Kind,
+		}
+		if p.AtKey() {
+			// collect segments of the key
+			frame.Key = []byte{}
+		}
+		p.frames = append(p.frames, frame)
+		return
+	case KindString, KindBytes:
+		if len(p.frames) > 0 {
+			top := &p.frames[len(p.frames)-1]
+			if top.Kind == KindStringBegin || top.Kind == KindBytesBegin {
+				if key, ok := top.Key.([]byte); ok {
+					switch segment := token.Value.(type) {
+					case string:
+						top.Key = append(key, segment...)
+					case []byte:
+						top.Key = append(key, segment...)
+					}
+				}
+				return
+			}
+		}
+	case KindStringEnd, KindBytesEnd:
+		if len(p.frames) > 0 {
+			top := p.frames[len(p.frames)-1]
+			p.frames = p.frames[:len(p.frames)-1]
+			value = nil
+			if key, ok := top.Key.([]byte); ok {
+				if top.Kind == KindStringBegin {
+					value = string(key)
+				} else {
+					value = key
+				}
+			}
+		}
+	case KindArrayEnd, KindObjectEnd, KindMapEnd, KindTupleEnd:
+		if len(p.frames) > 0 {
+			p.frames = p.frames[:len(p.frames)-1]
+		}
+	}
+	for len(p.frames) > 0 {
+		top := &p.frames[len(p.frames)-1]
+		if top.Kind == KindTypeName || top.Kind == KindPointerID {
+			// prefix completed with the value
+			p.frames = p.frames[:len(p.frames)-1]
+			continue
+		}
+		if (top.Kind == KindObject || top.Kind == KindMap) && top.N%2 == 0 {
+			top.Key = value
+		}
+		top.N++
+		break
+	}
+}
@@ -0,0 +1,92 @@
+package sb
+
+import "io"
+
+type Peekable struct {
+	stream Stream
+	buffer Tokens
+	eof    bool
+}
+
+func NewPeekable(stream Stream) *Peekable {
+	return &Peekable{
+		stream: stream,
+	}
+}
+
+func (p *Peekable) read(token *Token) error {
+	if p.eof {
+		return nil
+	}
+	if err := p.stream.Next(token); err != nil {
+		return err
+	}
+	if token.Invalid() {
+		p.eof = true
+	}
+	return nil
+}
+
+func (p *Peekable) Next(token *Token) error {
+	if len(p.buffer) > 0 {
+		*token = p.buffer[0]
+		p.buffer = p.buffer[1:]
+		return nil
+	}
+	return p.read(token)
+}
+
+func (p *Peekable) Stream() Stream {
+	var proc Proc
+	proc = func(token *Token) (Proc, error) {
+		if err := p.Next(token); err != nil {
+			return nil, err
+		}
+		if token.Invalid() {
+			return nil, nil
+		}
+		return proc, nil
+	}
+	return &proc
+}
+
+// Peek returns at most n next tokens without consuming them
+func (p *Peekable) Peek(n int) (Tokens, error) {
+	for len(p.buffer) < n && !p.eof {
+		var token Token
+		if err := p.read(&token); err != nil {
+			return nil, err
+		}
+		if token.Valid() {
+			p.buffer = append(p.buffer, token)
+		}
+	}
+	if n > len(p.buffer) {
+		n = len(p.buffer)
+	}
+	return append(Tokens(nil), p.buffer[:n]...), nil
+}
+
+func (p *Peekable) Unread(token Token) {
+	p.buffer = append(Tokens{token}, p.buffer...)
+}
+
+// NextValue returns tokens of the next value, or io.EOF if stream ends
+func (p *Peekable) NextValue() (Tokens, error) {
+	var tokens Tokens
+	if err := Copy(
+		p.Stream(),
+		CollectValueTokens(&tokens),
+	); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, io.EOF
+	}
+	return tokens, nil
+}
+
+func (p *Peekable) SkipValue() error {
+	_, err := p.NextValue()
+	return err
+}
@@ -0,0 +1,130 @@
+package sb
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPeekable(t *testing.T) {
+	p := NewPeekable(ConcatStreams(
+		Marshal([]int{1, 2}),
+		Marshal("foo"),
+		Marshal(42),
+	))
+
+	tokens, err := p.Peek(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 || tokens[0].Kind != KindArray || tokens[1].Value != 1 {
+		t.Fatalf("got %v", tokens)
+	}
+	// peek does not consume
+	tokens, err = p.Peek(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].Kind != KindArray {
+		t.Fatal()
+	}
+
+	var ints []int
+	if err := Copy(p.Stream(), Unmarshal(&ints)); err != nil {
+		t.Fatal(err)
+	}
+	if len(ints) != 2 {
+		t.Fatal()
+	}
+
+	value, err := p.NextValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(value) != 1 || value[0].Value != "foo" {
+		t.Fatal()
+	}
+
+	// unread
+	for i := len(value) - 1; i >= 0; i-- {
+		p.Unread(value[i])
+	}
+	if err := p.SkipValue(); err != nil {
+		t.Fatal(err)
+	}
+
+	var token Token
+	if err := p.Next(&token); err != nil {
+		t.Fatal(err)
+	}
+	if token.Value != 42 {
+		t.Fatal()
+	}
+
+	// end
+	tokens, err = p.Peek(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 0 {
+		t.Fatal()
+	}
+	if _, err := p.NextValue(); err != io.EOF {
+		t.Fatal()
+	}
+	if err := p.SkipValue(); err != io.EOF {
+		t.Fatal()
+	}
+	p.Unread(token)
+	token.Reset()
+	if err := p.Next(&token); err != nil {
+		t.Fatal(err)
+	}
+	if token.Value != 42 {
+		t.Fatal()
+	}
+
+	// incomplete value
+	p = NewPeekable(Tokens{{Kind: KindArray}}.Iter())
+	if _, err := p.NextValue(); !is(err, io.ErrUnexpectedEOF) {
+		t.Fatal()
+	}
+}
+
+func TestPeekableUnion(t *testing.T) {
+	// decode a JSON value that is either a number or a list of numbers
+	decode := func(input string) ([]float64, error) {
+		p := NewPeekable(DecodeJson(strings.NewReader(input), nil))
+		tokens, err := p.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) == 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		var ret []float64
+		if tokens[0].Kind == KindArray {
+			err = Copy(p.Stream(), Unmarshal(&ret))
+		} else {
+			var f float64
+			err = Copy(p.Stream(), Unmarshal(&f))
+			ret = append(ret, f)
+		}
+		return ret, err
+	}
+
+	fs, err := decode(`[1, 2, 3]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 3 {
+		t.Fatal()
+	}
+	fs, err = decode(`4`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 1 || fs[0] != 4 {
+		t.Fatal()
+	}
+}
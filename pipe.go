@@ -0,0 +1,91 @@
+package sb
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type TokenPipe struct {
+	tokens      chan Token
+	done        chan struct{}
+	closeOnce   sync.Once
+	writeClosed atomic.Bool
+	err         error
+}
+
+func NewTokenPipe(bufferTokens int) *TokenPipe {
+	return &TokenPipe{
+		tokens: make(chan Token, bufferTokens),
+		done:   make(chan struct{}),
+	}
+}
+
+// Pipe returns the two ends of a TokenPipe.
+// use NewTokenPipe and CloseWithError to abort the pipe
+func Pipe(bufferTokens int) (Sink, Stream) {
+	p := NewTokenPipe(bufferTokens)
+	return p.Sink, p.Stream()
+}
+
+func (p *TokenPipe) Sink(token *Token) (Sink, error) {
+	select {
+	case <-p.done:
+		return nil, p.err
+	default:
+	}
+	if p.writeClosed.Load() {
+		return nil, io.ErrClosedPipe
+	}
+	if token.Invalid() {
+		// end of stream
+		if p.writeClosed.CompareAndSwap(false, true) {
+			close(p.tokens)
+		}
+		return nil, nil
+	}
+	select {
+	case p.tokens <- *token:
+		return p.Sink, nil
+	case <-p.done:
+		return nil, p.err
+	}
+}
+
+func (p *TokenPipe) Stream() Stream {
+	proc := Proc(p.next)
+	return &proc
+}
+
+func (p *TokenPipe) next(token *Token) (Proc, error) {
+	select {
+	case t, ok := <-p.tokens:
+		if !ok {
+			select {
+			case <-p.done:
+				return nil, p.err
+			default:
+			}
+			return nil, nil
+		}
+		*token = t
+		return p.next, nil
+	case <-p.done:
+		return nil, p.err
+	}
+}
+
+// CloseWithError aborts the pipe. pending and later Sink and Stream calls return err
+func (p *TokenPipe) CloseWithError(err error) {
+	if err == nil {
+		err = io.ErrClosedPipe
+	}
+	p.closeOnce.Do(func() {
+		p.err = err
+		close(p.done)
+	})
+}
+
+func (p *TokenPipe) Close() {
+	p.CloseWithError(nil)
+}
@@ -0,0 +1,128 @@
+package sb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	type Foo struct {
+		I int
+		S []string
+		M map[int]string
+	}
+	value := Foo{
+		I: 42,
+		M: map[int]string{},
+	}
+	for i := 0; i < 1000; i++ {
+		value.S = append(value.S, fmt.Sprintf("%d", i))
+		value.M[i] = fmt.Sprintf("%d", i)
+	}
+
+	for _, n := range []int{0, 1, 16} {
+		sink, stream := Pipe(n)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- Copy(Marshal(value), sink)
+		}()
+		buf := new(bytes.Buffer)
+		var tokens Tokens
+		if err := Copy(
+			ConcatStreams(
+				Tee(stream, CollectTokens(&tokens)),
+				Marshal(1),
+			),
+			Encode(buf),
+		); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+		if MustCompare(tokens.Iter(), Marshal(value)) != 0 {
+			t.Fatal()
+		}
+		var v Foo
+		var i int
+		decoder := Decode(buf)
+		if err := Copy(decoder, Unmarshal(&v)); err != nil {
+			t.Fatal(err)
+		}
+		if err := Copy(decoder, Unmarshal(&i)); err != nil {
+			t.Fatal(err)
+		}
+		if v.I != 42 || len(v.S) != 1000 || len(v.M) != 1000 || i != 1 {
+			t.Fatal()
+		}
+	}
+}
+
+func TestPipeError(t *testing.T) {
+	// writer error
+	p := NewTokenPipe(1)
+	bad := fmt.Errorf("bad")
+	go func() {
+		sink, err := p.Sink(&Token{Kind: KindArray})
+		if err != nil {
+			panic(err)
+		}
+		if sink == nil {
+			panic("should not be nil")
+		}
+		p.CloseWithError(bad)
+	}()
+	var ints []int
+	if err := Copy(p.Stream(), Unmarshal(&ints)); !is(err, bad) {
+		t.Fatal()
+	}
+
+	// reader close
+	p = NewTokenPipe(0)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Copy(Marshal(make([]int, 1000)), p.Sink)
+	}()
+	var token Token
+	if err := p.Stream().Next(&token); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	if err := <-errCh; !is(err, io.ErrClosedPipe) {
+		t.Fatal()
+	}
+	token.Reset()
+	if err := p.Stream().Next(&token); !is(err, io.ErrClosedPipe) {
+		t.Fatal()
+	}
+
+	// write after end of stream
+	sink, stream := Pipe(10)
+	if err := Copy(Marshal(1), sink); err != nil {
+		t.Fatal(err)
+	}
+	if err := Copy(Marshal(2), sink); !is(err, io.ErrClosedPipe) {
+		t.Fatalf("got %v", err)
+	}
+	var i int
+	if err := Copy(stream, Unmarshal(&i)); err != nil {
+		t.Fatal(err)
+	}
+	if i != 1 {
+		t.Fatal()
+	}
+
+	// producer abort
+	p = NewTokenPipe(0)
+	go func() {
+		if _, err := p.Sink(&Token{Kind: KindArray}); err != nil {
+			panic(err)
+		}
+		p.CloseWithError(bad)
+	}()
+	if err := Copy(p.Stream(), Unmarshal(&ints)); !is(err, bad) {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package sb
+
+import (
+	"reflect"
+
+	"github.com/reusee/e5"
+)
+
+type pointerKey struct {
+	Type    reflect.Type
+	Pointer uintptr
+}
+
+type pointerEntry struct {
+	Value reflect.Value
+	// Value is the unmarshal target, not an allocated pointer
+	IsTarget bool
+}
+
+type pointerTable struct {
+	ids     map[pointerKey]int
+	entries []pointerEntry
+}
+
+func newPointerTable() *pointerTable {
+	return &pointerTable{
+		ids: make(map[pointerKey]int),
+	}
+}
+
+func marshalPointer(ctx Ctx, value reflect.Value, token *Token, cont Proc) Proc {
+	key := pointerKey{
+		Type:    value.Type(),
+		Pointer: value.Pointer(),
+	}
+	if id, ok := ctx.pointers.ids[key]; ok {
+		token.Kind = KindPointerRef
+		token.Value = id
+		return cont
+	}
+	id := len(ctx.pointers.ids)
+	ctx.pointers.ids[key] = id
+	token.Kind = KindPointerID
+	token.Value = id
+	return ctx.Marshal(ctx, value.Elem(), cont)
+}
+
+func unmarshalPointerID(ctx Ctx, target reflect.Value, id int, cont Sink) (Sink, error) {
+	if id != len(ctx.pointers.entries) {
+		return nil, we.With(e5.With(BadPointerID), e5.Info("pointer id: %d", id))(UnmarshalError)
+	}
+	if !target.IsValid() || target.Kind() != reflect.Ptr || target.IsNil() {
+		return nil, we.With(BadTargetType)(UnmarshalError)
+	}
+
+	if valueType := target.Type().Elem(); valueType.Kind() == reflect.Ptr {
+		// allocate and register before unmarshaling, so that cyclic references can be resolved
+		ptr := reflect.New(valueType.Elem())
+		ctx.pointers.entries = append(ctx.pointers.entries, pointerEntry{
+			Value: ptr,
+		})
+		return notNull(ctx, ctx.Unmarshal(
+			ctx,
+			ptr,
+			func(token *Token) (Sink, error) {
+				target.Elem().Set(ptr)
+				return cont.Sink(token)
+			},
+		)), nil
+	}
+
+	// non-pointer target, references to this id will get copies
+	ctx.pointers.entries = append(ctx.pointers.entries, pointerEntry{
+		Value:    target,
+		IsTarget: true,
+	})
+	return notNull(ctx, ctx.Unmarshal(ctx, target, cont)), nil
+}
+
+func unmarshalPointerRef(ctx Ctx, target reflect.Value, id int) error {
+	if id < 0 || id >= len(ctx.pointers.entries) {
+		return we.With(e5.With(BadPointerID), e5.Info("pointer id: %d", id))(UnmarshalError)
+	}
+	if !target.IsValid() || target.Kind() != reflect.Ptr || target.IsNil() {
+		return we.With(BadTargetType)(UnmarshalError)
+	}
+	entry := ctx.pointers.entries[id]
+	valueType := target.Type().Elem()
+	if !entry.IsTarget && entry.Value.Type().AssignableTo(valueType) {
+		target.Elem().Set(entry.Value)
+		return nil
+	}
+	if entry.Value.Elem().Type().AssignableTo(valueType) {
+		target.Elem().Set(entry.Value.Elem())
+		return nil
+	}
+	return we.With(TypeMismatch(KindPointerRef, valueType.Kind()))(UnmarshalError)
+}
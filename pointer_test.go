@@ -0,0 +1,201 @@
+package sb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"reflect"
+	"testing"
+)
+
+func TestSharedPointers(t *testing.T) {
+	type Foo struct {
+		A *int
+		B *int
+		C int
+	}
+	i := 42
+	var foo Foo
+	buf := new(bytes.Buffer)
+	if err := Copy(
+		MarshalCtx(DefaultCtx.SharePointers(), Foo{
+			A: &i,
+			B: &i,
+			C: 1,
+		}),
+		Encode(buf),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := Copy(
+		Decode(buf),
+		UnmarshalValue(DefaultCtx.SharePointers(), reflect.ValueOf(&foo), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if foo.A != foo.B {
+		t.Fatal()
+	}
+	if *foo.A != 42 || foo.C != 1 {
+		t.Fatal()
+	}
+
+	// copy to non-pointer target
+	var bar struct {
+		A int
+		B int
+	}
+	if err := Copy(
+		MarshalCtx(DefaultCtx.SharePointers(), Foo{
+			A: &i,
+			B: &i,
+		}),
+		UnmarshalValue(DefaultCtx.SharePointers(), reflect.ValueOf(&bar), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if bar.A != 42 || bar.B != 42 {
+		t.Fatal()
+	}
+
+	// not enabled
+	err := Copy(
+		MarshalCtx(DefaultCtx.SharePointers(), Foo{
+			A: &i,
+			B: &i,
+		}),
+		Unmarshal(&foo),
+	)
+	if !is(err, BadTokenKind) {
+		t.Fatal()
+	}
+}
+
+func TestSharedPointersCyclic(t *testing.T) {
+	type Node struct {
+		Value int
+		Next  *Node
+	}
+	a := &Node{Value: 1}
+	b := &Node{Value: 2}
+	c := &Node{Value: 3}
+	a.Next = b
+	b.Next = c
+	c.Next = a
+
+	buf := new(bytes.Buffer)
+	if err := Copy(
+		MarshalCtx(DefaultCtx.SharePointers(), a),
+		Encode(buf),
+	); err != nil {
+		t.Fatal(err)
+	}
+	var node *Node
+	if err := Copy(
+		Decode(bytes.NewReader(buf.Bytes())),
+		UnmarshalValue(DefaultCtx.SharePointers(), reflect.ValueOf(&node), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if node.Value != 1 || node.Next.Value != 2 || node.Next.Next.Value != 3 {
+		t.Fatal()
+	}
+	if node.Next.Next.Next != node {
+		t.Fatal()
+	}
+
+	// round trip
+	buf2 := new(bytes.Buffer)
+	if err := Copy(
+		MarshalCtx(DefaultCtx.SharePointers(), node),
+		Encode(buf2),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Fatal()
+	}
+
+	// self reference
+	type P *P
+	var p P
+	p = &p
+	var p2 P
+	if err := Copy(
+		MarshalCtx(DefaultCtx.SharePointers(), p),
+		UnmarshalValue(DefaultCtx.SharePointers(), reflect.ValueOf(&p2), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if *p2 != p2 {
+		t.Fatal()
+	}
+
+	// bad reference
+	err := Copy(
+		Tokens{
+			{Kind: KindPointerRef, Value: 0},
+		}.Iter(),
+		UnmarshalValue(DefaultCtx.SharePointers(), reflect.ValueOf(&node), nil),
+	)
+	if !is(err, BadPointerID) {
+		t.Fatal()
+	}
+}
+
+func TestSharedPointersHash(t *testing.T) {
+	type Node struct {
+		Value int
+		Next  *Node
+	}
+	newRing := func() *Node {
+		a := &Node{Value: 1}
+		b := &Node{Value: 2}
+		a.Next = b
+		b.Next = a
+		return a
+	}
+
+	var h1, h2 []byte
+	if err := Copy(
+		MarshalCtx(DefaultCtx.SharePointers(), newRing()),
+		Hash(sha256.New, &h1, nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := Copy(
+		MarshalCtx(DefaultCtx.SharePointers(), newRing()),
+		Hash(sha256.New, &h2, nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(h1, h2) {
+		t.Fatal()
+	}
+
+	tree := MustTreeFromStream(MarshalCtx(DefaultCtx.SharePointers(), newRing()))
+	if err := tree.FillHash(sha256.New); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(h1, tree.Hash) {
+		t.Fatal()
+	}
+
+	// shared and copied values have different hashes
+	i := 42
+	if err := Copy(
+		MarshalCtx(DefaultCtx.SharePointers(), []*int{&i, &i}),
+		Hash(sha256.New, &h1, nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	j := 42
+	if err := Copy(
+		MarshalCtx(DefaultCtx.SharePointers(), []*int{&i, &j}),
+		Hash(sha256.New, &h2, nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(h1, h2) {
+		t.Fatal()
+	}
+}
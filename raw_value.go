@@ -0,0 +1,93 @@
+package sb
+
+import (
+	"bytes"
+	"hash"
+	"io"
+	"reflect"
+
+	"github.com/reusee/e5"
+)
+
+// RawValue holds tokens of one value, including prefix tokens like KindTypeName
+type RawValue Tokens
+
+var _ SBMarshaler = RawValue(nil)
+
+func (r RawValue) MarshalSB(ctx Ctx, cont Proc) Proc {
+	if len(r) == 0 {
+		return Nil.MarshalSB(ctx, cont)
+	}
+	return IterTokens(Tokens(r), 0, cont)
+}
+
+var _ SBUnmarshaler = new(RawValue)
+
+// tokenCapturer is implemented by unmarshalers that keep tokens verbatim
+// literal tokens are not converted for them
+type tokenCapturer interface {
+	captureTokens()
+}
+
+var tokenCapturerType = reflect.TypeOf((*tokenCapturer)(nil)).Elem()
+
+var _ tokenCapturer = new(RawValue)
+
+func (*RawValue) captureTokens() {}
+
+func (r *RawValue) UnmarshalSB(ctx Ctx, cont Sink) Sink {
+	return captureValue(ctx, func(tokens Tokens) {
+		*r = RawValue(tokens)
+	}, cont)
+}
+
+// captureValue collects tokens of one value and passes them to fn
+func captureValue(ctx Ctx, fn func(Tokens), cont Sink) Sink {
+	var tokens Tokens
+	collect := CollectValueTokens(&tokens)
+	var sink Sink
+	sink = func(token *Token) (Sink, error) {
+		if token.Invalid() {
+			return nil, we.With(WithPath(ctx), io.ErrUnexpectedEOF)(UnmarshalError)
+		}
+		var err error
+		collect, err = collect(token)
+		if err != nil {
+			return nil, we.With(WithPath(ctx), e5.With(UnmarshalError))(err)
+		}
+		if collect == nil {
+			fn(tokens)
+			return cont, nil
+		}
+		return sink, nil
+	}
+	return sink
+}
+
+func (r RawValue) Iter() *Proc {
+	return Tokens(r).Iter()
+}
+
+func (r RawValue) Unmarshal(target any) error {
+	return Copy(r.Iter(), Unmarshal(target))
+}
+
+func (r RawValue) UnmarshalCtx(ctx Ctx, target any) error {
+	return Copy(r.Iter(), UnmarshalValue(ctx, reflect.ValueOf(target), nil))
+}
+
+func (r RawValue) Hash(newState func() hash.Hash) ([]byte, error) {
+	var sum []byte
+	if err := Copy(r.Iter(), Hash(newState, &sum, nil)); err != nil {
+		return nil, err
+	}
+	return sum, nil
+}
+
+func (r RawValue) Bytes() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := Copy(r.Iter(), Encode(buf)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,131 @@
+package sb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRawValue(t *testing.T) {
+	type Payload struct {
+		Foo int
+		Bar []string
+	}
+	type Envelope struct {
+		Type    string
+		Payload RawValue
+		Tagged  RawValue
+	}
+
+	payload := Payload{
+		Foo: 42,
+		Bar: []string{"a", "b"},
+	}
+	env := struct {
+		Type    string
+		Payload Payload
+		Tagged  any
+	}{
+		Type:    "payload",
+		Payload: payload,
+		Tagged:  definedInt(1),
+	}
+
+	var e Envelope
+	if err := Copy(Marshal(env), Unmarshal(&e)); err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != "payload" {
+		t.Fatal()
+	}
+	if len(e.Tagged) != 2 || e.Tagged[0].Kind != KindTypeName {
+		t.Fatalf("got %+v", e.Tagged)
+	}
+
+	// deferred unmarshal
+	var p Payload
+	if err := e.Payload.Unmarshal(&p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Foo != 42 || len(p.Bar) != 2 {
+		t.Fatal()
+	}
+	var tagged any
+	if err := e.Tagged.UnmarshalCtx(DefaultCtx, &tagged); err != nil {
+		t.Fatal(err)
+	}
+	if tagged != definedInt(1) {
+		t.Fatalf("got %#v", tagged)
+	}
+
+	// hash
+	sum, err := e.Payload.Hash(sha256.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected []byte
+	if err := Copy(Marshal(payload), Hash(sha256.New, &expected, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sum, expected) {
+		t.Fatal()
+	}
+
+	// bytes
+	bs, err := e.Payload.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = Payload{}
+	if err := Copy(Decode(bytes.NewReader(bs)), Unmarshal(&p)); err != nil {
+		t.Fatal(err)
+	}
+	if p.Foo != 42 {
+		t.Fatal()
+	}
+
+	// forward verbatim
+	if MustCompare(Marshal(e), Marshal(env)) != 0 {
+		t.Fatal()
+	}
+
+	// empty
+	var empty RawValue
+	tokens, err := TokensFromStream(Marshal(empty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].Kind != KindNil {
+		t.Fatal()
+	}
+
+	// literal
+	var raw RawValue
+	if err := Copy(
+		DecodeJson(strings.NewReader(`[1, 2]`), nil),
+		Unmarshal(&raw),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 4 || raw[1].Kind != KindLiteral {
+		t.Fatalf("got %+v", raw)
+	}
+	var ints []int
+	if err := raw.Unmarshal(&ints); err != nil {
+		t.Fatal(err)
+	}
+	if len(ints) != 2 || ints[1] != 2 {
+		t.Fatal()
+	}
+
+	// incomplete
+	err = Copy(
+		Tokens{{Kind: KindArray}}.Iter(),
+		Unmarshal(&raw),
+	)
+	if !is(err, io.ErrUnexpectedEOF) {
+		t.Fatal()
+	}
+}
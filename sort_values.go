@@ -0,0 +1,262 @@
+package sb
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"os"
+	"slices"
+)
+
+type SortOptions struct {
+	// extract sort key from value. values are sorted by key, then by value
+	Key func(value Stream) (Stream, error)
+	// drop values equal to the previous one
+	Dedup bool
+	// bytes of buffered records before spilling a sorted run to temp file
+	MemoryLimit int
+	// directory for temp files, default to os.TempDir()
+	TempDir string
+}
+
+type sortRecord struct {
+	// key encoding of the extracted key, nil if SortOptions.Key is nil
+	Key []byte
+	// encoded value
+	Value []byte
+}
+
+func compareSortRecords(a, b sortRecord) int {
+	if res := bytes.Compare(a.Key, b.Key); res != 0 {
+		return res
+	}
+	return MustCompareBytes(a.Value, b.Value)
+}
+
+// SortValues sorts values of in by Compare order and writes them to out, spilling sorted runs to temp files when over the memory limit.
+// values are stored with Encode and compared with CompareBytes.
+// extracted keys are stored in key encoding (see EncodeKey) and compared with bytes.Compare
+func SortValues(in Stream, out Sink, opts SortOptions) (err error) {
+	memoryLimit := opts.MemoryLimit
+	if memoryLimit <= 0 {
+		memoryLimit = 64 * 1024 * 1024
+	}
+
+	var records []sortRecord
+	var size int
+	var runs []*os.File
+	defer func() {
+		for _, f := range runs {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	spill := func() error {
+		slices.SortFunc(records, compareSortRecords)
+		f, err := os.CreateTemp(opts.TempDir, "sb-sort-")
+		if err != nil {
+			return err
+		}
+		runs = append(runs, f)
+		w := bufio.NewWriter(f)
+		buf := make([]byte, 0, binary.MaxVarintLen64)
+		for _, record := range records {
+			for _, bs := range [][]byte{record.Key, record.Value} {
+				if _, err := w.Write(binary.AppendUvarint(buf[:0], uint64(len(bs)))); err != nil {
+					return err
+				}
+				if _, err := w.Write(bs); err != nil {
+					return err
+				}
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		records = records[:0]
+		size = 0
+		return nil
+	}
+
+	for {
+		var tokens Tokens
+		if err := Copy(in, CollectValueTokens(&tokens)); err != nil {
+			return err
+		}
+		if len(tokens) == 0 {
+			break
+		}
+		var record sortRecord
+		if opts.Key != nil {
+			key, err := opts.Key(tokens.Iter())
+			if err != nil {
+				return err
+			}
+			record.Key, err = encodeKeyBytes(key)
+			if err != nil {
+				return err
+			}
+		}
+		buf := new(bytes.Buffer)
+		if err := Copy(tokens.Iter(), Encode(buf)); err != nil {
+			return err
+		}
+		record.Value = buf.Bytes()
+		records = append(records, record)
+		size += len(record.Key) + len(record.Value)
+		if size >= memoryLimit {
+			if err := spill(); err != nil {
+				return err
+			}
+		}
+	}
+
+	var next func() (sortRecord, bool, error)
+	if len(runs) == 0 {
+		// in memory
+		slices.SortFunc(records, compareSortRecords)
+		next = func() (sortRecord, bool, error) {
+			if len(records) == 0 {
+				return sortRecord{}, false, nil
+			}
+			record := records[0]
+			records = records[1:]
+			return record, true, nil
+		}
+
+	} else {
+		if len(records) > 0 {
+			if err := spill(); err != nil {
+				return err
+			}
+		}
+		h := new(sortRunHeap)
+		for _, f := range runs {
+			run := &sortRun{
+				r: bufio.NewReader(f),
+			}
+			ok, err := run.next()
+			if err != nil {
+				return err
+			}
+			if ok {
+				*h = append(*h, run)
+			}
+		}
+		heap.Init(h)
+		next = func() (sortRecord, bool, error) {
+			if h.Len() == 0 {
+				return sortRecord{}, false, nil
+			}
+			run := (*h)[0]
+			record := run.record
+			ok, err := run.next()
+			if err != nil {
+				return sortRecord{}, false, err
+			}
+			if ok {
+				heap.Fix(h, 0)
+			} else {
+				heap.Pop(h)
+			}
+			return record, true, nil
+		}
+	}
+
+	var last *sortRecord
+	for out != nil {
+		record, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if opts.Dedup && last != nil &&
+			compareSortRecords(record, *last) == 0 {
+			continue
+		}
+		last = &record
+		value := Decode(bytes.NewReader(record.Value))
+		for out != nil {
+			var token Token
+			if err := value.Next(&token); err != nil {
+				return err
+			}
+			if token.Invalid() {
+				break
+			}
+			out, err = out(&token)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if out != nil {
+		if _, err := out(new(Token)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type sortRun struct {
+	r      *bufio.Reader
+	record sortRecord
+}
+
+func (s *sortRun) next() (bool, error) {
+	var bss [2][]byte
+	for i := range bss {
+		l, err := binary.ReadUvarint(s.r)
+		if i == 0 && err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		bss[i] = make([]byte, l)
+		if _, err := io.ReadFull(s.r, bss[i]); err != nil {
+			return false, err
+		}
+	}
+	s.record = sortRecord{
+		Key:   bss[0],
+		Value: bss[1],
+	}
+	return true, nil
+}
+
+type sortRunHeap []*sortRun
+
+var _ heap.Interface = new(sortRunHeap)
+
+func (s sortRunHeap) Len() int {
+	return len(s)
+}
+
+func (s sortRunHeap) Less(i, j int) bool {
+	return compareSortRecords(s[i].record, s[j].record) < 0
+}
+
+func (s sortRunHeap) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s *sortRunHeap) Push(x any) {
+	*s = append(*s, x.(*sortRun))
+}
+
+func (s *sortRunHeap) Pop() any {
+	old := *s
+	x := old[len(old)-1]
+	*s = old[:len(old)-1]
+	return x
+}
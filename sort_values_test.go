@@ -0,0 +1,139 @@
+package sb
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSortValues(t *testing.T) {
+	in := new(bytes.Buffer)
+	var ints []int
+	for i := 0; i < 1000; i++ {
+		n := rand.Intn(2000) - 1000
+		ints = append(ints, n)
+		if err := Copy(Marshal(n), Encode(in)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sort.Ints(ints)
+
+	for _, limit := range []int{0, 128} {
+		out := new(bytes.Buffer)
+		if err := SortValues(
+			Decode(bytes.NewReader(in.Bytes())),
+			Encode(out),
+			SortOptions{
+				MemoryLimit: limit,
+				TempDir:     t.TempDir(),
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+		decoder := Decode(out)
+		for _, expected := range ints {
+			var n int
+			if err := Copy(decoder, Unmarshal(&n)); err != nil {
+				t.Fatal(err)
+			}
+			if n != expected {
+				t.Fatalf("expected %d, got %d", expected, n)
+			}
+		}
+	}
+}
+
+func TestSortValuesNegativeZero(t *testing.T) {
+	for _, limit := range []int{0, 1} {
+		out := new(bytes.Buffer)
+		if err := SortValues(
+			ConcatStreams(Marshal(1.0), Marshal(math.Copysign(0, -1)), Marshal(-1.0)),
+			Encode(out),
+			SortOptions{
+				MemoryLimit: limit,
+				TempDir:     t.TempDir(),
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+		var fs []float64
+		decoder := Decode(out)
+		for i := 0; i < 3; i++ {
+			var f float64
+			if err := Copy(decoder, Unmarshal(&f)); err != nil {
+				t.Fatal(err)
+			}
+			fs = append(fs, f)
+		}
+		if fs[0] != -1 || fs[1] != 0 || !math.Signbit(fs[1]) || fs[2] != 1 {
+			t.Fatalf("got %v", fs)
+		}
+	}
+}
+
+func TestSortValuesKeyAndDedup(t *testing.T) {
+	type Record struct {
+		ID   int
+		Name string
+	}
+	var tokens Tokens
+	records := []Record{
+		{3, "c"},
+		{1, "a"},
+		{2, "b"},
+		{1, "a"},
+		{-1, "z"},
+		{1, "a"},
+		{2, "a"},
+	}
+	for _, r := range records {
+		if err := Copy(Marshal(r), CollectTokens(&tokens)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, limit := range []int{0, 1} {
+		var sorted []Record
+		var collect Sink
+		collect = func(token *Token) (Sink, error) {
+			if token.Invalid() {
+				return nil, nil
+			}
+			var r Record
+			return UnmarshalValue(DefaultCtx, reflect.ValueOf(&r), func(token *Token) (Sink, error) {
+				sorted = append(sorted, r)
+				return collect(token)
+			})(token)
+		}
+		if err := SortValues(
+			tokens.Iter(),
+			collect,
+			SortOptions{
+				Key: func(value Stream) (Stream, error) {
+					var r Record
+					if err := Copy(value, Unmarshal(&r)); err != nil {
+						return nil, err
+					}
+					return Marshal(r.ID), nil
+				},
+				Dedup:       true,
+				MemoryLimit: limit,
+				TempDir:     t.TempDir(),
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(sorted, []Record{
+			{-1, "z"},
+			{1, "a"},
+			{2, "a"},
+			{2, "b"},
+			{3, "c"},
+		}) {
+			t.Fatalf("got %v", sorted)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package sb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+type structField struct {
+	Name     string
+	Index    []int
+	Type     reflect.Type
+	Required bool
+	// tokens to unmarshal if the field is absent, nil if not set
+	Default Tokens
+}
+
+type structFieldSet struct {
+	Fields []structField
+	ByName map[string]int
+	// index of the UnknownFields field, nil if not exists
+	UnknownFields []int
+	// whether some fields are required or have default values
+	TrackPresence bool
+}
+
+var structFieldsMap sync.Map
+
+// getStructFields returns marshaling fields of struct type t
+// embedded structs and pointers to structs are flattened, like encoding/json
+// tag `sb:"nested"` disables flattening of an embedded field
+// tag `sb:"required"` and `sb:"default=..."` set field presence rules, see field_rules.go
+// on name conflicts, the shallowest field wins, and fields at the same depth are all dropped
+func getStructFields(t reflect.Type) *structFieldSet {
+	if v, ok := structFieldsMap.Load(t); ok {
+		return v.(*structFieldSet)
+	}
+
+	type scan struct {
+		Type  reflect.Type
+		Index []int
+	}
+	type candidate struct {
+		structField
+		Depth int
+	}
+	var candidates []candidate
+	var unknownFields []int
+	visited := make(map[reflect.Type]bool)
+	current := []scan{{Type: t}}
+	for depth := 0; len(current) > 0; depth++ {
+		var next []scan
+		for _, s := range current {
+			visited[s.Type] = true
+		}
+		for _, s := range current {
+			for i := 0; i < s.Type.NumField(); i++ {
+				field := s.Type.Field(i)
+				if field.PkgPath != "" && !field.Anonymous {
+					// unexported
+					continue
+				}
+				index := make([]int, len(s.Index)+1)
+				copy(index, s.Index)
+				index[len(s.Index)] = i
+
+				if field.Type == unknownFieldsType {
+					if depth == 0 {
+						unknownFields = index
+					}
+					continue
+				}
+
+				tag := parseFieldTag(field.Tag)
+
+				if field.Anonymous && !tag.Nested {
+					embeddedType := field.Type
+					if embeddedType.Kind() == reflect.Ptr && embeddedType.Name() == "" {
+						embeddedType = embeddedType.Elem()
+					}
+					if embeddedType.Kind() == reflect.Struct {
+						if !visited[embeddedType] {
+							next = append(next, scan{
+								Type:  embeddedType,
+								Index: index,
+							})
+						}
+						continue
+					}
+				}
+				if field.PkgPath != "" {
+					// unexported embedded non-struct type
+					continue
+				}
+
+				candidates = append(candidates, candidate{
+					structField: structField{
+						Name:     field.Name,
+						Index:    index,
+						Type:     field.Type,
+						Required: tag.Required,
+						Default:  tag.Default,
+					},
+					Depth: depth,
+				})
+			}
+		}
+		current = next
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Name != candidates[j].Name {
+			return candidates[i].Name < candidates[j].Name
+		}
+		return candidates[i].Depth < candidates[j].Depth
+	})
+	var fields []structField
+	for i := 0; i < len(candidates); {
+		j := i + 1
+		for j < len(candidates) && candidates[j].Name == candidates[i].Name {
+			j++
+		}
+		if j-i == 1 || candidates[i+1].Depth > candidates[i].Depth {
+			fields = append(fields, candidates[i].structField)
+		}
+		i = j
+	}
+
+	// in declaration order
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].Index, fields[j].Index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	set := &structFieldSet{
+		Fields:        fields,
+		ByName:        make(map[string]int, len(fields)),
+		UnknownFields: unknownFields,
+	}
+	for i, field := range fields {
+		set.ByName[field.Name] = i
+	}
+	applyFieldRules(t, set)
+	v, _ := structFieldsMap.LoadOrStore(t, set)
+	return v.(*structFieldSet)
+}
+
+// fieldByIndexAlloc returns the field of struct value v, allocating nil embedded pointers
+// nil pointers to unexported struct types are not settable, like encoding/json, an error is returned
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct: %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
@@ -0,0 +1,276 @@
+package sb
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type testEmbedBase struct {
+	ID   int
+	Name string
+}
+
+type testEmbedMeta struct {
+	Name    string
+	Version int
+}
+
+type testEmbedExtra struct {
+	Tags []string
+}
+
+type testEmbedOuter struct {
+	testEmbedBase
+	*testEmbedExtra
+	Title string
+}
+
+type TestEmbedExported struct {
+	Foo int
+}
+
+type TestEmbedPointer struct {
+	Bar string
+}
+
+type testEmbed struct {
+	TestEmbedExported
+	*TestEmbedPointer
+	Baz int
+}
+
+type testEmbedNested struct {
+	TestEmbedExported `sb:"nested"`
+	Baz               int
+}
+
+type TestEmbedA struct {
+	Name string
+	A    int
+}
+
+type TestEmbedB struct {
+	Name string
+	B    int
+}
+
+type TestEmbedC struct {
+	TestEmbedA
+}
+
+type testEmbedAmbiguous struct {
+	TestEmbedA
+	TestEmbedB
+}
+
+type testEmbedShallow struct {
+	TestEmbedC
+	TestEmbedB
+}
+
+type TestEmbedRecursive struct {
+	*TestEmbedRecursive
+	Foo int
+}
+
+func testFieldNames(t *testing.T, v any) []string {
+	t.Helper()
+	var names []string
+	for _, field := range getStructFields(reflect.TypeOf(v)).Fields {
+		names = append(names, field.Name)
+	}
+	return names
+}
+
+func TestEmbeddedStructFields(t *testing.T) {
+	for _, c := range []struct {
+		value    any
+		expected []string
+	}{
+		{testEmbed{}, []string{"Foo", "Bar", "Baz"}},
+		{testEmbedNested{}, []string{"TestEmbedExported", "Baz"}},
+		// fields of unexported embedded structs are promoted
+		{testEmbedOuter{}, []string{"ID", "Name", "Tags", "Title"}},
+		// ambiguous names are dropped
+		{testEmbedAmbiguous{}, []string{"A", "B"}},
+		// shallower field wins
+		{testEmbedShallow{}, []string{"A", "Name", "B"}},
+		{TestEmbedRecursive{}, []string{"Foo"}},
+	} {
+		names := testFieldNames(t, c.value)
+		if !reflect.DeepEqual(names, c.expected) {
+			t.Fatalf("%T: got %v", c.value, names)
+		}
+	}
+}
+
+func TestEmbeddedStructMarshal(t *testing.T) {
+	v := testEmbed{
+		TestEmbedExported: TestEmbedExported{
+			Foo: 1,
+		},
+		TestEmbedPointer: &TestEmbedPointer{
+			Bar: "bar",
+		},
+		Baz: 2,
+	}
+	tokens, err := TokensFromStream(Marshal(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Tokens{
+		{Kind: KindObject},
+		{Kind: KindString, Value: "Foo"},
+		{Kind: KindInt, Value: 1},
+		{Kind: KindString, Value: "Bar"},
+		{Kind: KindString, Value: "bar"},
+		{Kind: KindString, Value: "Baz"},
+		{Kind: KindInt, Value: 2},
+		{Kind: KindObjectEnd},
+	}
+	if MustCompare(tokens.Iter(), expected.Iter()) != 0 {
+		t.Fatalf("got %+v", tokens)
+	}
+
+	// round trip, embedded pointer allocated on demand
+	var v2 testEmbed
+	if err := Copy(tokens.Iter(), Unmarshal(&v2)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, v2) {
+		t.Fatalf("got %+v", v2)
+	}
+
+	// nil embedded pointer
+	v.TestEmbedPointer = nil
+	tokens, err = TokensFromStream(Marshal(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 6 {
+		t.Fatalf("got %+v", tokens)
+	}
+	var v3 testEmbed
+	if err := Copy(tokens.Iter(), Unmarshal(&v3)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, v3) {
+		t.Fatalf("got %+v", v3)
+	}
+
+	// nested
+	nested := testEmbedNested{
+		TestEmbedExported: TestEmbedExported{
+			Foo: 1,
+		},
+		Baz: 2,
+	}
+	tokens, err = TokensFromStream(Marshal(nested))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[1].Value != "TestEmbedExported" || tokens[2].Kind != KindObject {
+		t.Fatalf("got %+v", tokens)
+	}
+	var nested2 testEmbedNested
+	if err := Copy(tokens.Iter(), Unmarshal(&nested2)); err != nil {
+		t.Fatal(err)
+	}
+	if nested != nested2 {
+		t.Fatal()
+	}
+
+	// shallow
+	shallow := testEmbedShallow{
+		TestEmbedC: TestEmbedC{
+			TestEmbedA: TestEmbedA{
+				Name: "a",
+				A:    1,
+			},
+		},
+		TestEmbedB: TestEmbedB{
+			Name: "b",
+			B:    2,
+		},
+	}
+	var shallow2 testEmbedShallow
+	if err := Copy(Marshal(shallow), Unmarshal(&shallow2)); err != nil {
+		t.Fatal(err)
+	}
+	if shallow2.TestEmbedB.Name != "b" || shallow2.A != 1 || shallow2.B != 2 || shallow2.TestEmbedA.Name != "" {
+		t.Fatalf("got %+v", shallow2)
+	}
+
+	// unexported embedded
+	outer := testEmbedOuter{
+		testEmbedBase: testEmbedBase{
+			ID:   1,
+			Name: "foo",
+		},
+		testEmbedExtra: &testEmbedExtra{
+			Tags: []string{"bar"},
+		},
+		Title: "baz",
+	}
+	// same fields as encoding/json
+	jsonBytes, err := json.Marshal(outer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJson testEmbedOuter
+	if err := Copy(
+		DecodeJson(bytes.NewReader(jsonBytes), nil),
+		Unmarshal(&fromJson),
+	); !is(err, UnmarshalError) {
+		// nil unexported embedded pointer
+		t.Fatalf("got %v", err)
+	}
+	fromJson.testEmbedExtra = new(testEmbedExtra)
+	if err := Copy(
+		DecodeJson(bytes.NewReader(jsonBytes), nil),
+		Unmarshal(&fromJson),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(outer, fromJson) {
+		t.Fatalf("got %+v", fromJson)
+	}
+	tokens, err = TokensFromStream(Marshal(outer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[1].Value != "ID" || tokens[3].Value != "Name" ||
+		tokens[5].Value != "Tags" || tokens[9].Value != "Title" {
+		t.Fatalf("got %+v", tokens)
+	}
+	outer2 := testEmbedOuter{
+		testEmbedExtra: new(testEmbedExtra),
+	}
+	if err := Copy(Marshal(outer), Unmarshal(&outer2)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(outer, outer2) {
+		t.Fatalf("got %+v", outer2)
+	}
+
+	// nil unexported embedded pointer is not settable
+	var outer3 testEmbedOuter
+	err = Copy(Marshal(outer), Unmarshal(&outer3))
+	if !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+	if json.Unmarshal(jsonBytes, &outer3) == nil {
+		t.Fatal()
+	}
+	// absent fields do not need allocation
+	outer.testEmbedExtra = nil
+	var outer4 testEmbedOuter
+	if err := Copy(Marshal(outer), Unmarshal(&outer4)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(outer, outer4) {
+		t.Fatalf("got %+v", outer4)
+	}
+}
@@ -0,0 +1,326 @@
+package sb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/reusee/e5"
+)
+
+var tableMagic = []byte("sbtable1")
+
+const tableFooterLen = 8 + 8 + 8
+
+type TableOption interface {
+	IsTableOption()
+}
+
+type TableBlockSize int
+
+func (TableBlockSize) IsTableOption() {}
+
+type tableIndexEntry struct {
+	FirstKey []byte
+	Offset   int64
+	Length   int64
+}
+
+type TableWriter struct {
+	w         io.Writer
+	blockSize int
+	offset    int64
+	block     []byte
+	blockKey  []byte
+	index     []tableIndexEntry
+	lastKey   Tokens
+	count     int
+	keyBuf    *bytes.Buffer
+	valueBuf  *bytes.Buffer
+	closed    bool
+}
+
+func NewTableWriter(w io.Writer, options ...TableOption) *TableWriter {
+	t := &TableWriter{
+		w:         w,
+		blockSize: 4096,
+		keyBuf:    new(bytes.Buffer),
+		valueBuf:  new(bytes.Buffer),
+	}
+	for _, option := range options {
+		switch option := option.(type) {
+		case TableBlockSize:
+			t.blockSize = int(option)
+		}
+	}
+	return t
+}
+
+func (t *TableWriter) Add(key Stream, value Stream) error {
+	if t.closed {
+		return TableClosed
+	}
+
+	keyTokens, err := TokensFromStream(key)
+	if err != nil {
+		return err
+	}
+	if t.count > 0 {
+		res, err := Compare(t.lastKey.Iter(), keyTokens.Iter())
+		if err != nil {
+			return err
+		}
+		if res >= 0 {
+			return KeyNotInOrder
+		}
+	}
+
+	t.keyBuf.Reset()
+	if err := Copy(keyTokens.Iter(), EncodeKey(t.keyBuf)); err != nil {
+		return err
+	}
+	t.valueBuf.Reset()
+	if err := Copy(value, Encode(t.valueBuf)); err != nil {
+		return err
+	}
+
+	if len(t.block) == 0 {
+		t.blockKey = append(t.blockKey[:0], t.keyBuf.Bytes()...)
+	}
+	t.block = binary.AppendUvarint(t.block, uint64(t.keyBuf.Len()))
+	t.block = append(t.block, t.keyBuf.Bytes()...)
+	t.block = binary.AppendUvarint(t.block, uint64(t.valueBuf.Len()))
+	t.block = append(t.block, t.valueBuf.Bytes()...)
+	t.lastKey = keyTokens
+	t.count++
+
+	if len(t.block) >= t.blockSize {
+		if err := t.flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *TableWriter) flush() error {
+	if len(t.block) == 0 {
+		return nil
+	}
+	if _, err := t.w.Write(t.block); err != nil {
+		return err
+	}
+	t.index = append(t.index, tableIndexEntry{
+		FirstKey: append([]byte(nil), t.blockKey...),
+		Offset:   t.offset,
+		Length:   int64(len(t.block)),
+	})
+	t.offset += int64(len(t.block))
+	t.block = t.block[:0]
+	return nil
+}
+
+func (t *TableWriter) Close() error {
+	if t.closed {
+		return TableClosed
+	}
+	t.closed = true
+
+	if err := t.flush(); err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := Copy(
+		Marshal(t.index),
+		Encode(buf),
+	); err != nil {
+		return err
+	}
+	footer := make([]byte, 0, tableFooterLen)
+	footer = binary.LittleEndian.AppendUint64(footer, uint64(t.offset))
+	footer = binary.LittleEndian.AppendUint64(footer, uint64(buf.Len()))
+	footer = append(footer, tableMagic...)
+	if _, err := buf.Write(footer); err != nil { // NOCOVER
+		return err
+	}
+	if _, err := t.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type TableReader struct {
+	r     io.ReaderAt
+	index []tableIndexEntry
+}
+
+func NewTableReader(r io.ReaderAt, size int64) (*TableReader, error) {
+	if size < tableFooterLen {
+		return nil, we.With(e5.Info("file too small"))(BadTable)
+	}
+	footer := make([]byte, tableFooterLen)
+	// io.ReaderAt may return io.EOF with a full read at the end of input
+	if n, err := r.ReadAt(footer, size-tableFooterLen); n != len(footer) {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, we.With(e5.With(BadTable))(err)
+	}
+	if !bytes.Equal(footer[16:], tableMagic) {
+		return nil, we.With(e5.Info("bad magic"))(BadTable)
+	}
+	indexOffset := int64(binary.LittleEndian.Uint64(footer[:8]))
+	indexLen := int64(binary.LittleEndian.Uint64(footer[8:16]))
+	if indexOffset < 0 || indexLen < 0 || indexOffset+indexLen != size-tableFooterLen {
+		return nil, we.With(e5.Info("bad index offset"))(BadTable)
+	}
+
+	var index []tableIndexEntry
+	if err := Copy(
+		Decode(io.NewSectionReader(r, indexOffset, indexLen)),
+		Unmarshal(&index),
+	); err != nil {
+		return nil, we.With(e5.With(BadTable))(err)
+	}
+
+	return &TableReader{
+		r:     r,
+		index: index,
+	}, nil
+}
+
+func (t *TableReader) Get(key Stream) (Stream, error) {
+	keyBytes, err := encodeKeyBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	var value Stream
+	if err := t.iterate(keyBytes, func(k, v []byte) (bool, error) {
+		if bytes.Equal(k, keyBytes) {
+			value = Decode(bytes.NewReader(v))
+		}
+		return false, nil
+	}); err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, NotFound
+	}
+	return value, nil
+}
+
+func (t *TableReader) Range(lower, upper Stream, fn func(key, value Stream) error) error {
+	var lowerBytes, upperBytes []byte
+	var err error
+	if lower != nil {
+		lowerBytes, err = encodeKeyBytes(lower)
+		if err != nil {
+			return err
+		}
+	}
+	if upper != nil {
+		upperBytes, err = encodeKeyBytes(upper)
+		if err != nil {
+			return err
+		}
+	}
+	return t.iterate(lowerBytes, func(k, v []byte) (bool, error) {
+		if upper != nil && bytes.Compare(k, upperBytes) >= 0 {
+			return false, nil
+		}
+		return callTableFunc(fn, k, v)
+	})
+}
+
+// Prefix calls fn with entries whose keys start with the tokens of prefix
+// tokens are matched as a whole, so a string prefix does not match longer strings,
+// and prefix should be unterminated to match collections, like Tuple and leading elements without TupleEnd
+func (t *TableReader) Prefix(prefix Stream, fn func(key, value Stream) error) error {
+	prefixBytes, err := encodeKeyBytes(prefix)
+	if err != nil {
+		return err
+	}
+	return t.iterate(prefixBytes, func(k, v []byte) (bool, error) {
+		if !bytes.HasPrefix(k, prefixBytes) {
+			return false, nil
+		}
+		return callTableFunc(fn, k, v)
+	})
+}
+
+func callTableFunc(fn func(key, value Stream) error, k, v []byte) (bool, error) {
+	if err := fn(
+		DecodeKey(bytes.NewReader(k)),
+		Decode(bytes.NewReader(v)),
+	); is(err, Break) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func encodeKeyBytes(key Stream) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := Copy(key, EncodeKey(buf)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// iterate calls fn with entries not less than from, until fn returns false
+func (t *TableReader) iterate(from []byte, fn func(k, v []byte) (bool, error)) error {
+	// last block with first key not greater than from
+	i := sort.Search(len(t.index), func(i int) bool {
+		return bytes.Compare(t.index[i].FirstKey, from) > 0
+	}) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	for ; i < len(t.index); i++ {
+		entry := t.index[i]
+		block := make([]byte, entry.Length)
+		if n, err := t.r.ReadAt(block, entry.Offset); n != len(block) {
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			return we.With(e5.With(BadTable), e5.With(Offset(entry.Offset)))(err)
+		}
+		for len(block) > 0 {
+			k, rest, err := readTableBytes(block)
+			if err != nil {
+				return we.With(e5.With(Offset(entry.Offset)))(err)
+			}
+			v, rest, err := readTableBytes(rest)
+			if err != nil {
+				return we.With(e5.With(Offset(entry.Offset)))(err)
+			}
+			block = rest
+			if bytes.Compare(k, from) < 0 {
+				continue
+			}
+			more, err := fn(k, v)
+			if err != nil {
+				return err
+			}
+			if !more {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func readTableBytes(bs []byte) ([]byte, []byte, error) {
+	l, n := binary.Uvarint(bs)
+	if n <= 0 || uint64(len(bs)-n) < l {
+		return nil, nil, we.With(e5.Info("bad record"))(BadTable)
+	}
+	bs = bs[n:]
+	return bs[:l], bs[l:], nil
+}
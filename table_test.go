@@ -0,0 +1,216 @@
+package sb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "table")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewTableWriter(f, TableBlockSize(128))
+	for i := -500; i < 500; i++ {
+		if err := w.Add(
+			Marshal(Tuple{i / 10, i}),
+			Marshal(fmt.Sprintf("%d", i)),
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Add(Marshal(Tuple{0, 0}), Marshal(0)); !is(err, KeyNotInOrder) {
+		t.Fatal()
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(Marshal(Tuple{1000, 1000}), Marshal(0)); !is(err, TableClosed) {
+		t.Fatal()
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewTableReader(f, info.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.index) < 2 {
+		t.Fatal()
+	}
+
+	// get
+	for i := -500; i < 500; i++ {
+		value, err := r.Get(Marshal(Tuple{i / 10, i}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s string
+		if err := Copy(value, Unmarshal(&s)); err != nil {
+			t.Fatal(err)
+		}
+		if s != fmt.Sprintf("%d", i) {
+			t.Fatal()
+		}
+	}
+	if _, err := r.Get(Marshal(Tuple{0, 1000})); !is(err, NotFound) {
+		t.Fatal()
+	}
+	if _, err := r.Get(Marshal(Tuple{-1000, 0})); !is(err, NotFound) {
+		t.Fatal()
+	}
+
+	// range
+	var keys []int
+	if err := r.Range(
+		Marshal(Tuple{-2, -20}),
+		Marshal(Tuple{2, 20}),
+		func(key, value Stream) error {
+			var k Tuple
+			if err := Copy(key, Unmarshal(&k)); err != nil {
+				return err
+			}
+			keys = append(keys, k[1].(int))
+			return nil
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 40 || keys[0] != -20 || keys[39] != 19 {
+		t.Fatalf("got %v", keys)
+	}
+
+	// unbounded range with break
+	keys = keys[:0]
+	if err := r.Range(nil, nil, func(key, value Stream) error {
+		var k Tuple
+		if err := Copy(key, Unmarshal(&k)); err != nil {
+			return err
+		}
+		keys = append(keys, k[1].(int))
+		if len(keys) == 10 {
+			return Break
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 10 || keys[0] != -500 {
+		t.Fatal()
+	}
+
+	// prefix
+	var values []string
+	if err := r.Prefix(
+		Tokens{
+			{Kind: KindTuple},
+			{Kind: KindInt, Value: 3},
+		}.Iter(),
+		func(key, value Stream) error {
+			var s string
+			if err := Copy(value, Unmarshal(&s)); err != nil {
+				return err
+			}
+			values = append(values, s)
+			return nil
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 10 || values[0] != "30" || values[9] != "39" {
+		t.Fatalf("got %v", values)
+	}
+
+	// terminated prefix matches whole keys only
+	n := 0
+	if err := r.Prefix(
+		Marshal(Tuple{3}),
+		func(key, value Stream) error {
+			n++
+			return nil
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("got %d", n)
+	}
+}
+
+// testEOFReaderAt returns io.EOF with full reads at the end of input
+type testEOFReaderAt struct {
+	*bytes.Reader
+}
+
+func (r testEOFReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.Reader.ReadAt(p, off)
+	if err == nil && off+int64(n) == r.Size() {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func TestTableReaderAtEOF(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := NewTableWriter(buf)
+	if err := w.Add(Marshal(1), Marshal(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewTableReader(testEOFReaderAt{bytes.NewReader(buf.Bytes())}, int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := r.Get(Marshal(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var i int
+	if err := Copy(value, Unmarshal(&i)); err != nil {
+		t.Fatal(err)
+	}
+	if i != 2 {
+		t.Fatal()
+	}
+}
+
+func TestBadTable(t *testing.T) {
+	if _, err := NewTableReader(bytes.NewReader(nil), 0); !is(err, BadTable) {
+		t.Fatal()
+	}
+	data := bytes.Repeat([]byte{1}, 32)
+	if _, err := NewTableReader(bytes.NewReader(data), int64(len(data))); !is(err, BadTable) {
+		t.Fatal()
+	}
+
+	buf := new(bytes.Buffer)
+	w := NewTableWriter(buf)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewTableReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(Marshal(42)); !is(err, NotFound) {
+		t.Fatal()
+	}
+}
@@ -41,22 +41,8 @@ func CollectTokens(tokens *Tokens) Sink {
 
 func CollectValueTokens(tokens *Tokens) Sink {
 	var sink Sink
-	type Frame struct {
-		Kind Kind
-		N    int
-	}
-	var stack []*Frame
+	var stack []Kind
 	sink = func(token *Token) (Sink, error) {
-		if len(stack) > 0 {
-			parent := stack[len(stack)-1]
-			if parent.Kind == KindTypeName && parent.N > 0 {
-				stack = stack[:len(stack)-1]
-				if len(stack) > 0 {
-					parent = stack[len(stack)-1]
-				}
-			}
-			parent.N++
-		}
 		if token.Invalid() {
 			if len(stack) > 0 {
 				return nil, io.ErrUnexpectedEOF
@@ -69,40 +55,31 @@ func CollectValueTokens(tokens *Tokens) Sink {
 			if len(stack) == 0 {
 				return nil, UnexpectedEndToken
 			}
-			if token.Kind != stack[len(stack)-1].Kind {
+			if token.Kind != stack[len(stack)-1] {
 				return nil, UnexpectedEndToken
 			}
 			stack = stack[:len(stack)-1]
-			if len(stack) == 0 {
-				return nil, nil
-			}
-			return sink, nil
 		case KindArray:
-			stack = append(stack, &Frame{
-				Kind: KindArrayEnd,
-			})
+			stack = append(stack, KindArrayEnd)
 			return sink, nil
 		case KindObject:
-			stack = append(stack, &Frame{
-				Kind: KindObjectEnd,
-			})
+			stack = append(stack, KindObjectEnd)
 			return sink, nil
 		case KindMap:
-			stack = append(stack, &Frame{
-				Kind: KindMapEnd,
-			})
+			stack = append(stack, KindMapEnd)
 			return sink, nil
 		case KindTuple:
-			stack = append(stack, &Frame{
-				Kind: KindTupleEnd,
-			})
+			stack = append(stack, KindTupleEnd)
 			return sink, nil
-		case KindTypeName:
-			stack = append(stack, &Frame{
-				Kind: KindTypeName,
-			})
+		case KindTypeName, KindPointerID:
+			stack = append(stack, token.Kind)
 			return sink, nil
 		}
+		// value completed, pop prefixes
+		for len(stack) > 0 &&
+			(stack[len(stack)-1] == KindTypeName || stack[len(stack)-1] == KindPointerID) {
+			stack = stack[:len(stack)-1]
+		}
 		if len(stack) > 0 {
 			return sink, nil
 		}
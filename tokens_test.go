@@ -123,4 +123,27 @@ func TestCollectValueTokens(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// prefixed value followed by sibling tokens
+	stream := Tokens{
+		{Kind: KindArray},
+		{Kind: KindTypeName, Value: "foo"},
+		{Kind: KindInt, Value: 42},
+		{Kind: KindPointerID, Value: 1},
+		{Kind: KindArray},
+		{Kind: KindArrayEnd},
+		{Kind: KindArrayEnd},
+		{Kind: KindTypeName, Value: "bar"},
+		{Kind: KindInt, Value: 1},
+		{Kind: KindString, Value: "rest"},
+	}.Iter()
+	for _, expected := range []int{7, 2, 1} {
+		tokens = tokens[:0]
+		if err := Copy(stream, CollectValueTokens(&tokens)); err != nil {
+			t.Fatal(err)
+		}
+		if len(tokens) != expected {
+			t.Fatalf("expected %d, got %d", expected, len(tokens))
+		}
+	}
+
 }
@@ -0,0 +1,231 @@
+package sb
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/reusee/e5"
+)
+
+func MapTokens(
+	stream Stream,
+	fn func(path Path, token Token) (Token, error),
+) *Proc {
+	var tracker pathTracker
+	var proc Proc
+	proc = func(token *Token) (Proc, error) {
+		if err := stream.Next(token); err != nil {
+			return nil, err
+		}
+		if token.Invalid() {
+			return nil, nil
+		}
+		if isScalarKind(token.Kind) && !tracker.AtKey() {
+			path := tracker.Path()
+			mapped, err := fn(path, *token)
+			if err != nil {
+				return nil, we.With(e5.With(path))(err)
+			}
+			if !isScalarKind(mapped.Kind) {
+				return nil, we.With(e5.With(path), e5.With(mapped.Kind))(BadTokenKind)
+			}
+			*token = mapped
+		}
+		tracker.Push(token)
+		return proc, nil
+	}
+	return &proc
+}
+
+func ReplaceAt(
+	stream Stream,
+	path Path,
+	replacement Stream,
+) *Proc {
+	var tracker pathTracker
+	var replacing *validator
+	replaced := false
+	var proc Proc
+	proc = func(token *Token) (Proc, error) {
+
+		if replacing != nil {
+			if err := replacement.Next(token); err != nil {
+				return nil, err
+			}
+			if token.Invalid() && replacing.index == 0 {
+				return nil, we.With(e5.With(path))(io.ErrUnexpectedEOF)
+			}
+			if err := replacing.check(token); err != nil {
+				return nil, we.With(e5.With(path))(err)
+			}
+			tracker.Push(token)
+			if len(replacing.tracker.frames) == 0 {
+				// one value replaced
+				replacing = nil
+			}
+			return proc, nil
+		}
+
+		if err := stream.Next(token); err != nil {
+			return nil, err
+		}
+		if token.Invalid() {
+			return nil, nil
+		}
+		if !replaced && !tracker.AtKey() && !isEndKind(token.Kind) &&
+			pathEqual(tracker.Path(), path) {
+			if err := walkValue(stream, token, nil); err != nil {
+				return nil, err
+			}
+			replaced = true
+			replacing = new(validator)
+			token.Reset()
+			return proc, nil
+		}
+		tracker.Push(token)
+		return proc, nil
+	}
+	return &proc
+}
+
+func RenameFields(
+	stream Stream,
+	fn func(path Path, name string) string,
+) *Proc {
+	var tracker pathTracker
+	var proc Proc
+	proc = func(token *Token) (Proc, error) {
+		if err := stream.Next(token); err != nil {
+			return nil, err
+		}
+		if token.Invalid() {
+			return nil, nil
+		}
+		if token.Kind == KindString && tracker.AtKey() &&
+			tracker.frames[len(tracker.frames)-1].Kind == KindObject {
+			token.Value = fn(tracker.Path(), token.Value.(string))
+		}
+		tracker.Push(token)
+		return proc, nil
+	}
+	return &proc
+}
+
+// DropFields drops object fields and map entries whose key satisfies the predicate
+func DropFields(
+	stream Stream,
+	drop func(path Path, key Tokens) bool,
+) *Proc {
+	var tracker pathTracker
+	var pending Tokens
+	var proc Proc
+	proc = func(token *Token) (Proc, error) {
+		if len(pending) > 0 {
+			*token = pending[0]
+			pending = pending[1:]
+			tracker.Push(token)
+			return proc, nil
+		}
+
+		for {
+			if err := stream.Next(token); err != nil {
+				return nil, err
+			}
+			if token.Invalid() {
+				return nil, nil
+			}
+			if !tracker.AtKey() || isEndKind(token.Kind) {
+				break
+			}
+			var key Tokens
+			if err := walkValue(stream, token, func(t Token) {
+				key = append(key, t)
+			}); err != nil {
+				return nil, err
+			}
+			if !drop(tracker.Path(), key) {
+				*token = key[0]
+				pending = key[1:]
+				break
+			}
+			// drop value
+			var value Token
+			if err := stream.Next(&value); err != nil {
+				return nil, err
+			}
+			if value.Invalid() {
+				return nil, we.With(e5.With(tracker.Path()))(io.ErrUnexpectedEOF)
+			}
+			if err := walkValue(stream, &value, nil); err != nil {
+				return nil, err
+			}
+			token.Reset()
+		}
+
+		tracker.Push(token)
+		return proc, nil
+	}
+	return &proc
+}
+
+// walkValue reads the rest of the value starting with first, calling fn with every token if not nil
+func walkValue(stream Stream, first *Token, fn func(Token)) error {
+	depth := 0
+	token := *first
+	for {
+		if fn != nil {
+			fn(token)
+		}
+		switch token.Kind {
+		case KindArray, KindObject, KindMap, KindTuple, KindStringBegin, KindBytesBegin:
+			depth++
+		case KindArrayEnd, KindObjectEnd, KindMapEnd, KindTupleEnd, KindStringEnd, KindBytesEnd:
+			depth--
+			if depth < 0 {
+				return UnexpectedEndToken
+			}
+		}
+		if depth == 0 && token.Kind != KindTypeName && token.Kind != KindPointerID {
+			return nil
+		}
+		token.Reset()
+		if err := stream.Next(&token); err != nil {
+			return err
+		}
+		if token.Invalid() {
+			return io.ErrUnexpectedEOF
+		}
+	}
+}
+
+func isEndKind(kind Kind) bool {
+	switch kind {
+	case KindArrayEnd, KindObjectEnd, KindMapEnd, KindTupleEnd:
+		return true
+	}
+	return false
+}
+
+func isScalarKind(kind Kind) bool {
+	switch kind {
+	case KindInvalid,
+		KindArray, KindObject, KindMap, KindTuple,
+		KindArrayEnd, KindObjectEnd, KindMapEnd, KindTupleEnd,
+		KindTypeName, KindPointerID,
+		KindStringBegin, KindStringEnd, KindBytesBegin, KindBytesEnd:
+		return false
+	}
+	return true
+}
+
+func pathEqual(a, b Path) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,259 @@
+package sb
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+type transformTestValue struct {
+	Foo int
+	Bar []string
+	Baz map[string]int
+}
+
+var transformTestData = transformTestValue{
+	Foo: 1,
+	Bar: []string{"a", "b"},
+	Baz: map[string]int{"x": 1, "y": 2},
+}
+
+func TestMapTokens(t *testing.T) {
+	var v transformTestValue
+	if err := Copy(
+		Validate(MapTokens(
+			Marshal(transformTestData),
+			func(path Path, token Token) (Token, error) {
+				switch value := token.Value.(type) {
+				case int:
+					token.Value = value * 10
+				case string:
+					token.Value = strings.ToUpper(value) + path.String()
+				}
+				return token, nil
+			},
+		)),
+		Unmarshal(&v),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if v.Foo != 10 ||
+		v.Bar[0] != "A/Bar/0" || v.Bar[1] != "B/Bar/1" ||
+		v.Baz["x"] != 10 || v.Baz["y"] != 20 {
+		t.Fatalf("got %+v", v)
+	}
+
+	// not scalar
+	err := Copy(
+		MapTokens(
+			Marshal(42),
+			func(path Path, token Token) (Token, error) {
+				return Token{Kind: KindArray}, nil
+			},
+		),
+		Discard,
+	)
+	if !is(err, BadTokenKind) {
+		t.Fatal()
+	}
+}
+
+func TestReplaceAt(t *testing.T) {
+	var v transformTestValue
+	if err := Copy(
+		Validate(ReplaceAt(
+			Marshal(transformTestData),
+			Path{"Bar", 1},
+			Marshal("c"),
+		)),
+		Unmarshal(&v),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if v.Bar[1] != "c" || v.Bar[0] != "a" || v.Foo != 1 {
+		t.Fatalf("got %+v", v)
+	}
+
+	// compound value
+	v = transformTestValue{}
+	if err := Copy(
+		Validate(ReplaceAt(
+			Marshal(transformTestData),
+			Path{"Baz"},
+			Marshal(map[string]int{"z": 3}),
+		)),
+		Unmarshal(&v),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Baz) != 1 || v.Baz["z"] != 3 {
+		t.Fatalf("got %+v", v)
+	}
+
+	// root
+	var i int
+	if err := Copy(
+		ReplaceAt(Marshal("foo"), Path{}, Marshal(42)),
+		Unmarshal(&i),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if i != 42 {
+		t.Fatal()
+	}
+
+	// empty replacement
+	err := Copy(
+		ReplaceAt(Marshal([]int{1}), Path{0}, Tokens{}.Iter()),
+		Discard,
+	)
+	if !is(err, io.ErrUnexpectedEOF) {
+		t.Fatal()
+	}
+
+	// malformed replacement
+	err = Copy(
+		ReplaceAt(Marshal([]int{1}), Path{0}, Tokens{{Kind: KindArray}}.Iter()),
+		Discard,
+	)
+	if !is(err, ValidateError) {
+		t.Fatal()
+	}
+}
+
+func TestRenameFields(t *testing.T) {
+	var v struct {
+		FooX int
+		BarX []string
+		BazX map[string]int
+	}
+	if err := Copy(
+		Validate(RenameFields(
+			Marshal(transformTestData),
+			func(path Path, name string) string {
+				if len(path) != 0 {
+					t.Fatalf("got %v", path)
+				}
+				return name + "X"
+			},
+		)),
+		Unmarshal(&v),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if v.FooX != 1 || len(v.BarX) != 2 ||
+		// map keys not renamed
+		v.BazX["x"] != 1 {
+		t.Fatalf("got %+v", v)
+	}
+}
+
+func TestDropFields(t *testing.T) {
+	var v transformTestValue
+	if err := Copy(
+		Validate(DropFields(
+			Marshal(transformTestData),
+			func(path Path, key Tokens) bool {
+				return key[0].Value == "Bar" || key[0].Value == "x"
+			},
+		)),
+		Unmarshal(&v),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if v.Foo != 1 || len(v.Bar) != 0 || len(v.Baz) != 1 || v.Baz["y"] != 2 {
+		t.Fatalf("got %+v", v)
+	}
+
+	// compound keys
+	var m map[[2]int]int
+	if err := Copy(
+		Validate(DropFields(
+			Marshal(map[[2]int]int{{1, 2}: 1, {3, 4}: 2}),
+			func(path Path, key Tokens) bool {
+				return len(key) == 4 && key[1].Value == 1
+			},
+		)),
+		Unmarshal(&m),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 {
+		t.Fatalf("got %+v", m)
+	}
+}
+
+func TestTransformCompose(t *testing.T) {
+	buf := new(bytes.Buffer)
+	var tokens Tokens
+	var v []transformTestValue
+	if err := Copy(
+		Validate(DropFields(
+			RenameFields(
+				Tee(
+					ConcatStreams(
+						Tokens{{Kind: KindArray}}.Iter(),
+						Marshal(transformTestData),
+						ReplaceAt(
+							Marshal(transformTestData),
+							Path{"Foo"},
+							Marshal(2),
+						),
+						Tokens{{Kind: KindArrayEnd}}.Iter(),
+					),
+					CollectTokens(&tokens),
+				),
+				func(path Path, name string) string {
+					if name == "Qux" {
+						return "Bar"
+					}
+					return name
+				},
+			),
+			func(path Path, key Tokens) bool {
+				return key[0].Value == "Baz"
+			},
+		)),
+		Encode(buf),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := Copy(Decode(buf), Unmarshal(&v)); err != nil {
+		t.Fatal(err)
+	}
+	if len(v) != 2 || v[0].Foo != 1 || v[1].Foo != 2 || len(v[1].Baz) != 0 || len(v[1].Bar) != 2 {
+		t.Fatalf("got %+v", v)
+	}
+	if len(tokens) == 0 {
+		t.Fatal()
+	}
+
+	// deref
+	sum := []byte("foo")
+	var s []string
+	if err := Copy(
+		MapTokens(
+			Deref(
+				Tokens{
+					{Kind: KindArray},
+					{Kind: KindRef, Value: sum},
+					{Kind: KindArrayEnd},
+				}.Iter(),
+				func(bs []byte) (Stream, error) {
+					return Marshal("foo"), nil
+				},
+			),
+			func(path Path, token Token) (Token, error) {
+				token.Value = token.Value.(string) + "bar"
+				return token, nil
+			},
+		),
+		Unmarshal(&s),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 1 || s[0] != "foobar" {
+		t.Fatalf("got %v", s)
+	}
+}
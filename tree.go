@@ -87,9 +87,9 @@ func TreeFromStream(
 		}
 		parent := stack[len(stack)-1]
 		if parent.Token != nil &&
-			parent.Kind == KindTypeName &&
+			(parent.Kind == KindTypeName || parent.Kind == KindPointerID) &&
 			len(parent.Subs) > 0 {
-			// filled type name node
+			// filled type name or pointer id node
 			stack = stack[:len(stack)-1]
 			parent = stack[len(stack)-1]
 		}
@@ -97,7 +97,7 @@ func TreeFromStream(
 		switch token.Kind {
 		case KindArray, KindObject, KindMap, KindTuple:
 			stack = append(stack, node)
-		case KindTypeName:
+		case KindTypeName, KindPointerID:
 			stack = append(stack, node)
 		case KindArrayEnd, KindObjectEnd, KindMapEnd, KindTupleEnd:
 			if len(stack) == 1 {
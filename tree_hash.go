@@ -56,6 +56,7 @@ func (t *Tree) FillHash(
 		KindUint32,
 		KindUint64,
 		KindPointer,
+		KindPointerRef,
 		KindFloat32,
 		KindFloat64:
 
@@ -158,6 +159,14 @@ func (t *Tree) FillHash(
 			if _, err := state.Write((buf)); err != nil {
 				return err
 			}
+		case KindPointerRef:
+			var buf []byte
+			elem := bytesPool8.Get(&buf)
+			defer elem.Put()
+			binary.LittleEndian.PutUint64(buf, uint64(token.Value.(int)))
+			if _, err := state.Write(buf); err != nil {
+				return err
+			}
 		case KindFloat32:
 			var buf []byte
 			elem := bytesPool8.Get(&buf)
@@ -191,10 +200,21 @@ func (t *Tree) FillHash(
 		}
 		t.Hash = state.Sum(nil)
 
-	case KindTypeName:
-		// type name
-		if _, err := io.WriteString(state, token.Value.(string)); err != nil { // NOCOVER
-			return err
+	case KindTypeName, KindPointerID:
+		if token.Kind == KindTypeName {
+			// type name
+			if _, err := io.WriteString(state, token.Value.(string)); err != nil { // NOCOVER
+				return err
+			}
+		} else {
+			// pointer id
+			var buf []byte
+			elem := bytesPool8.Get(&buf)
+			defer elem.Put()
+			binary.LittleEndian.PutUint64(buf, uint64(token.Value.(int)))
+			if _, err := state.Write(buf); err != nil {
+				return err
+			}
 		}
 		// subs
 		for _, sub := range t.Subs {
@@ -0,0 +1,52 @@
+package sb
+
+import "reflect"
+
+type TypeCodec struct {
+	// nil means using the default marshaling
+	Marshal func(ctx Ctx, value reflect.Value, cont Proc) Proc
+	// target is a pointer to the value. nil means using the default unmarshaling
+	Unmarshal func(ctx Ctx, target reflect.Value, cont Sink) Sink
+}
+
+// TypeCodecs is an immutable registry of TypeCodec, consulted by MarshalValue and UnmarshalValue before built-in rules
+type TypeCodecs struct {
+	codecs map[reflect.Type]TypeCodec
+}
+
+// With returns a new registry with codec set for type t
+func (t TypeCodecs) With(typ reflect.Type, codec TypeCodec) TypeCodecs {
+	codecs := make(map[reflect.Type]TypeCodec, len(t.codecs)+1)
+	for k, v := range t.codecs {
+		codecs[k] = v
+	}
+	codecs[typ] = codec
+	return TypeCodecs{
+		codecs: codecs,
+	}
+}
+
+func (t TypeCodecs) Get(typ reflect.Type) (codec TypeCodec, ok bool) {
+	if len(t.codecs) == 0 {
+		return
+	}
+	codec, ok = t.codecs[typ]
+	return
+}
+
+func (c Ctx) WithTypeCodecs(codecs TypeCodecs) Ctx {
+	return c.withOptions(func(options *ctxOptions) {
+		options.TypeCodecs = codecs
+	})
+}
+
+func (c Ctx) WithTypeCodec(
+	typ reflect.Type,
+	marshal func(Ctx, reflect.Value, Proc) Proc,
+	unmarshal func(Ctx, reflect.Value, Sink) Sink,
+) Ctx {
+	return c.WithTypeCodecs(c.opts().TypeCodecs.With(typ, TypeCodec{
+		Marshal:   marshal,
+		Unmarshal: unmarshal,
+	}))
+}
@@ -0,0 +1,111 @@
+package sb
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestTypeCodec(t *testing.T) {
+	addrType := reflect.TypeOf(netip.Addr{})
+
+	marshalAddr := func(ctx Ctx, value reflect.Value, cont Proc) Proc {
+		bs := value.Interface().(netip.Addr).As16()
+		return ctx.Marshal(ctx, reflect.ValueOf(bs[:]), cont)
+	}
+	unmarshalAddr := func(ctx Ctx, target reflect.Value, cont Sink) Sink {
+		var bs []byte
+		return ctx.Unmarshal(ctx, reflect.ValueOf(&bs), func(token *Token) (Sink, error) {
+			if len(bs) != 16 {
+				return nil, we.With(WithPath(ctx), fmt.Errorf("bad address"))(UnmarshalError)
+			}
+			target.Elem().Set(reflect.ValueOf(netip.AddrFrom16([16]byte(bs)).Unmap()))
+			return cont.Sink(token)
+		})
+	}
+
+	type Host struct {
+		Name string
+		Addr netip.Addr
+	}
+	host := Host{
+		Name: "foo",
+		Addr: netip.MustParseAddr("10.0.0.1"),
+	}
+
+	base := DefaultCtx
+	ctx := base.WithTypeCodec(addrType, marshalAddr, unmarshalAddr)
+	if _, ok := base.opts().TypeCodecs.Get(addrType); ok {
+		t.Fatal()
+	}
+
+	tokens, err := TokensFromStream(MarshalCtx(ctx, host))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[4].Kind != KindBytes {
+		t.Fatalf("got %+v", tokens)
+	}
+
+	var h Host
+	if err := Copy(
+		tokens.Iter(),
+		UnmarshalValue(ctx, reflect.ValueOf(&h), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if h != host {
+		t.Fatalf("got %+v", h)
+	}
+
+	// pointer
+	var ptr *netip.Addr
+	if err := Copy(
+		MarshalCtx(ctx, &host.Addr),
+		UnmarshalValue(ctx, reflect.ValueOf(&ptr), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if *ptr != host.Addr {
+		t.Fatal()
+	}
+
+	// default codec
+	tokens, err = TokensFromStream(MarshalCtx(base, host))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[4].Kind != KindString {
+		t.Fatalf("got %+v", tokens)
+	}
+
+	// shared registry
+	codecs := TypeCodecs{}.With(addrType, TypeCodec{
+		Marshal: marshalAddr,
+	})
+	ctx2 := base.WithTypeCodecs(codecs)
+	tokens, err = TokensFromStream(MarshalCtx(ctx2, host))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[4].Kind != KindBytes {
+		t.Fatalf("got %+v", tokens)
+	}
+	// nil unmarshal uses default
+	if err := Copy(
+		MarshalCtx(base, host),
+		UnmarshalValue(ctx2, reflect.ValueOf(&h), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// error
+	err = Copy(
+		Marshal(Host{}),
+		UnmarshalValue(ctx, reflect.ValueOf(&h), nil),
+	)
+	if !is(err, UnmarshalError) {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package sb
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 )
 
@@ -38,11 +39,79 @@ func TypeName(t reflect.Type) (name string) {
 	return ""
 }
 
+var registerLock sync.Mutex
+
 func Register(t reflect.Type) {
 	name := TypeName(t)
 	if name == "" {
 		panic(fmt.Errorf("not defined type: %v", t))
 	}
-	registeredNameToType.LoadOrStore(name, t)
-	registeredTypeToName.LoadOrStore(t, name)
+	RegisterName(name, t)
+}
+
+// RegisterName registers t with a stable name, which is used in marshaling and unmarshaling
+func RegisterName(name string, t reflect.Type) {
+	if name == "" {
+		panic(fmt.Errorf("empty name: %v", t))
+	}
+	registerLock.Lock()
+	defer registerLock.Unlock()
+	checkRegisteredName(name, t)
+	if v, ok := registeredTypeToName.Load(t); ok && v.(string) != name {
+		panic(fmt.Errorf("conflicting registration: %v registered as %s, not %s", t, v, name))
+	}
+	registeredNameToType.Store(name, t)
+	registeredTypeToName.Store(t, name)
+}
+
+// RegisterAlias registers a legacy name of t, which is accepted in unmarshaling only
+func RegisterAlias(oldName string, t reflect.Type) {
+	if oldName == "" {
+		panic(fmt.Errorf("empty name: %v", t))
+	}
+	registerLock.Lock()
+	defer registerLock.Unlock()
+	checkRegisteredName(oldName, t)
+	registeredNameToType.Store(oldName, t)
+}
+
+// checkRegisteredName checks name against registered names and union tags, registerLock must be held
+func checkRegisteredName(name string, t reflect.Type) {
+	if v, ok := registeredNameToType.Load(name); ok && v.(reflect.Type) != t {
+		panic(fmt.Errorf("conflicting registration: %s registered as %v, not %v", name, v, t))
+	}
+	registeredUnions.Range(func(k, v any) bool {
+		if tagType, ok := v.(*union).tagToType[name]; ok && tagType != t {
+			panic(fmt.Errorf("conflicting registration: %s is a tag of %v in union %v, not %v", name, tagType, k, t))
+		}
+		return true
+	})
+}
+
+type RegistryEntry struct {
+	Name  string
+	Type  string
+	Alias bool
+}
+
+// Registry returns registered names and aliases, sorted by name
+func Registry() (entries []RegistryEntry) {
+	registeredNameToType.Range(func(k, v any) bool {
+		t := v.(reflect.Type)
+		typeName := TypeName(t)
+		if typeName == "" {
+			typeName = t.String()
+		}
+		name, _ := registeredTypeToName.Load(t)
+		entries = append(entries, RegistryEntry{
+			Name:  k.(string),
+			Type:  typeName,
+			Alias: name != k,
+		})
+		return true
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return
 }
@@ -31,3 +31,104 @@ func TestAliasTypeName(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+type testRenamed struct {
+	Foo int
+}
+
+type testRenamed2 struct {
+	Foo int
+}
+
+func init() {
+	RegisterName("renamed", reflect.TypeOf((*testRenamed)(nil)).Elem())
+	RegisterAlias("old.renamed", reflect.TypeOf((*testRenamed)(nil)).Elem())
+}
+
+func TestRegisterName(t *testing.T) {
+	typ := reflect.TypeOf((*testRenamed)(nil)).Elem()
+
+	// marshal emits the registered name
+	var v any = testRenamed{Foo: 42}
+	tokens, err := TokensFromStream(Marshal(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[0].Kind != KindTypeName || tokens[0].Value != "renamed" {
+		t.Fatalf("got %+v", tokens[0])
+	}
+
+	// unmarshal accepts the name and the alias
+	for _, name := range []string{"renamed", "old.renamed"} {
+		tokens[0].Value = name
+		var v any
+		if err := Copy(tokens.Iter(), Unmarshal(&v)); err != nil {
+			t.Fatal(err)
+		}
+		if r, ok := v.(testRenamed); !ok || r.Foo != 42 {
+			t.Fatalf("got %#v", v)
+		}
+	}
+
+	// idempotent
+	RegisterName("renamed", typ)
+	RegisterAlias("old.renamed", typ)
+
+	// conflicts
+	for i, fn := range []func(){
+		func() {
+			RegisterName("renamed", reflect.TypeOf((*testRenamed2)(nil)).Elem())
+		},
+		func() {
+			RegisterName("renamed2", typ)
+		},
+		func() {
+			RegisterAlias("renamed", reflect.TypeOf((*testRenamed2)(nil)).Elem())
+		},
+		func() {
+			RegisterAlias("old.renamed", reflect.TypeOf((*testRenamed2)(nil)).Elem())
+		},
+		func() {
+			Register(typ)
+		},
+		func() {
+			RegisterName("", typ)
+		},
+		func() {
+			RegisterAlias("", typ)
+		},
+		func() {
+			Register(reflect.TypeOf((*[]int)(nil)).Elem())
+		},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%d: should panic", i)
+				}
+			}()
+			fn()
+		}()
+	}
+
+	// registry
+	var entries []RegistryEntry
+	for _, entry := range Registry() {
+		if entry.Type == "github.com/reusee/sb.testRenamed" {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) != 2 ||
+		entries[0] != (RegistryEntry{Name: "old.renamed", Type: "github.com/reusee/sb.testRenamed", Alias: true}) ||
+		entries[1] != (RegistryEntry{Name: "renamed", Type: "github.com/reusee/sb.testRenamed"}) {
+		t.Fatalf("got %+v", entries)
+	}
+	// exportable
+	var exported []RegistryEntry
+	if err := Copy(Marshal(Registry()), Unmarshal(&exported)); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != len(Registry()) {
+		t.Fatal()
+	}
+}
@@ -0,0 +1,108 @@
+package sb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/reusee/e5"
+)
+
+type UnionVariant struct {
+	Tag  string
+	Type reflect.Type
+}
+
+type union struct {
+	tagToType map[string]reflect.Type
+	typeToTag map[reflect.Type]string
+	tags      []string
+}
+
+var registeredUnions sync.Map
+
+// RegisterUnion registers variants of interface type iface
+// values of iface will be marshaled as a KindTypeName token of the tag, followed by the concrete value
+// tags share the namespace of RegisterName and RegisterAlias, a tag registered as a name of another type panics
+func RegisterUnion(iface reflect.Type, variants ...UnionVariant) {
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Errorf("not interface type: %v", iface))
+	}
+	u := &union{
+		tagToType: make(map[string]reflect.Type),
+		typeToTag: make(map[reflect.Type]string),
+	}
+	for _, variant := range variants {
+		if variant.Tag == "" {
+			panic(fmt.Errorf("empty tag: %v", variant.Type))
+		}
+		if !variant.Type.Implements(iface) {
+			panic(fmt.Errorf("%v does not implement %v", variant.Type, iface))
+		}
+		if _, ok := u.tagToType[variant.Tag]; ok {
+			panic(fmt.Errorf("duplicated tag: %s", variant.Tag))
+		}
+		if _, ok := u.typeToTag[variant.Type]; ok {
+			panic(fmt.Errorf("duplicated type: %v", variant.Type))
+		}
+		u.tagToType[variant.Tag] = variant.Type
+		u.typeToTag[variant.Type] = variant.Tag
+		u.tags = append(u.tags, variant.Tag)
+	}
+	sort.Strings(u.tags)
+	registerLock.Lock()
+	defer registerLock.Unlock()
+	for _, tag := range u.tags {
+		checkRegisteredName(tag, u.tagToType[tag])
+	}
+	if _, loaded := registeredUnions.LoadOrStore(iface, u); loaded {
+		panic(fmt.Errorf("union already registered: %v", iface))
+	}
+}
+
+func marshalUnion(ctx Ctx, u *union, value reflect.Value, token *Token, cont Proc) (Proc, error) {
+	if value.IsNil() {
+		*token = Nil
+		return cont, nil
+	}
+	concrete := value.Elem()
+	tag, ok := u.typeToTag[concrete.Type()]
+	if !ok {
+		return nil, we.With(
+			WithPath(ctx),
+			e5.With(BadUnionVariant),
+			e5.Info("%v not in union %v", concrete.Type(), value.Type()),
+		)(MarshalError)
+	}
+	token.Kind = KindTypeName
+	token.Value = tag
+	return ctx.Marshal(ctx, concrete, cont), nil
+}
+
+func unmarshalUnion(ctx Ctx, u *union, target reflect.Value, token *Token, cont Sink) (Sink, error) {
+	if token.Kind != KindTypeName {
+		return nil, we.With(
+			BadUnionTag,
+			TypeMismatch(token.Kind, reflect.Interface),
+			e5.Info("allowed tags: %s", strings.Join(u.tags, ", ")),
+		)(UnmarshalError)
+	}
+	t, ok := u.tagToType[token.Value.(string)]
+	if !ok {
+		return nil, we.With(
+			BadUnionTag,
+			e5.Info("unknown tag %q, allowed tags: %s", token.Value, strings.Join(u.tags, ", ")),
+		)(UnmarshalError)
+	}
+	v := reflect.New(t)
+	return notNull(ctx, ctx.Unmarshal(
+		ctx,
+		v,
+		func(token *Token) (Sink, error) {
+			target.Elem().Set(v.Elem())
+			return cont.Sink(token)
+		},
+	)), nil
+}
@@ -0,0 +1,179 @@
+package sb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testShape interface {
+	Area() float64
+}
+
+type testCircle struct {
+	R float64
+}
+
+func (c testCircle) Area() float64 {
+	return 3 * c.R * c.R
+}
+
+type testRect struct {
+	W, H float64
+}
+
+func (r *testRect) Area() float64 {
+	return r.W * r.H
+}
+
+type testSquare float64
+
+func (s testSquare) Area() float64 {
+	return float64(s * s)
+}
+
+func init() {
+	RegisterUnion(
+		reflect.TypeOf((*testShape)(nil)).Elem(),
+		UnionVariant{"circle", reflect.TypeOf((*testCircle)(nil)).Elem()},
+		UnionVariant{"rect", reflect.TypeOf((*testRect)(nil))},
+	)
+}
+
+func TestUnion(t *testing.T) {
+	type Drawing struct {
+		Shapes []testShape
+		Main   testShape
+		None   testShape
+	}
+	drawing := Drawing{
+		Shapes: []testShape{
+			testCircle{R: 1},
+			&testRect{W: 2, H: 3},
+		},
+		Main: testCircle{R: 2},
+	}
+
+	tokens, err := TokensFromStream(Marshal(drawing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[3].Kind != KindTypeName || tokens[3].Value != "circle" {
+		t.Fatalf("got %+v", tokens[3])
+	}
+
+	var d Drawing
+	if err := Copy(tokens.Iter(), Unmarshal(&d)); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Shapes) != 2 ||
+		d.Shapes[0].(testCircle).R != 1 ||
+		d.Shapes[1].(*testRect).H != 3 ||
+		d.Main.Area() != 12 ||
+		d.None != nil {
+		t.Fatalf("got %+v", d)
+	}
+
+	// pointer to interface
+	var shape testShape = &testRect{W: 1, H: 2}
+	var shape2 testShape
+	if err := Copy(Marshal(&shape), Unmarshal(&shape2)); err != nil {
+		t.Fatal(err)
+	}
+	if shape2.Area() != 2 {
+		t.Fatal()
+	}
+
+	// not a variant
+	shape = testSquare(2)
+	err = Copy(Marshal(&shape), Discard)
+	if !is(err, MarshalError) || !is(err, BadUnionVariant) {
+		t.Fatal()
+	}
+
+	// unknown tag
+	err = Copy(
+		Tokens{
+			{Kind: KindTypeName, Value: "square"},
+			{Kind: KindFloat64, Value: 2.0},
+		}.Iter(),
+		Unmarshal(&shape2),
+	)
+	if !is(err, UnmarshalError) || !is(err, BadUnionTag) {
+		t.Fatal()
+	}
+	if !strings.Contains(err.Error(), "allowed tags: circle, rect") {
+		t.Fatalf("got %v", err)
+	}
+
+	// no tag
+	err = Copy(Marshal(42), Unmarshal(&shape2))
+	if !is(err, BadUnionTag) {
+		t.Fatal()
+	}
+	var path Path
+	if err := Copy(
+		Tokens{
+			{Kind: KindObject},
+			{Kind: KindString, Value: "Main"},
+			{Kind: KindInt, Value: 42},
+			{Kind: KindObjectEnd},
+		}.Iter(),
+		Unmarshal(&d),
+	); !is(err, BadUnionTag) || !as(err, &path) || path.String() != "/Main" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+type testShapeClash interface {
+	Area() float64
+}
+
+func TestBadRegisterUnion(t *testing.T) {
+	iface := reflect.TypeOf((*testShape)(nil)).Elem()
+	circle := reflect.TypeOf((*testCircle)(nil)).Elem()
+	square := reflect.TypeOf((*testSquare)(nil)).Elem()
+	RegisterName("test.union.square", square)
+	for i, fn := range []func(){
+		// tags and names share namespace
+		func() {
+			RegisterName("rect", square)
+		},
+		func() {
+			RegisterAlias("circle", square)
+		},
+		func() {
+			RegisterUnion(
+				reflect.TypeOf((*testShapeClash)(nil)).Elem(),
+				UnionVariant{"test.union.square", circle},
+			)
+		},
+		func() {
+			RegisterUnion(circle)
+		},
+		func() {
+			RegisterUnion(iface, UnionVariant{"", circle})
+		},
+		func() {
+			RegisterUnion(iface, UnionVariant{"foo", reflect.TypeOf((*testRect)(nil)).Elem()})
+		},
+		func() {
+			RegisterUnion(iface, UnionVariant{"foo", circle}, UnionVariant{"foo", reflect.TypeOf((*testRect)(nil))})
+		},
+		func() {
+			RegisterUnion(iface, UnionVariant{"foo", circle}, UnionVariant{"bar", circle})
+		},
+		func() {
+			RegisterUnion(iface)
+		},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%d: should panic", i)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -0,0 +1,54 @@
+package sb
+
+import (
+	"reflect"
+	"sort"
+)
+
+// UnknownFields captures unknown struct fields in unmarshaling
+// a struct with a field of this type will store unknown fields in it, and re-emit them after known fields in marshaling
+// the field is reset when unmarshaling an object, except in MergePatch mode, where entries are replaced by name
+// unknown fields are still rejected in Strict mode
+type UnknownFields []UnknownField
+
+type UnknownField struct {
+	Name  string
+	Value Tokens
+}
+
+var unknownFieldsType = reflect.TypeOf((*UnknownFields)(nil)).Elem()
+
+func marshalUnknownFields(fields UnknownFields, cont Proc) Proc {
+	// sorted by name
+	fields = append(fields[:0:0], fields...)
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+	var proc Proc
+	proc = func(token *Token) (Proc, error) {
+		if len(fields) == 0 {
+			return cont, nil
+		}
+		field := fields[0]
+		fields = fields[1:]
+		token.Kind = KindString
+		token.Value = field.Name
+		return IterTokens(field.Value, 0, proc), nil
+	}
+	return proc
+}
+
+func unmarshalUnknownField(ctx Ctx, target *UnknownFields, name string, cont Sink) Sink {
+	return captureValue(ctx, func(value Tokens) {
+		for i := range *target {
+			if (*target)[i].Name == name {
+				(*target)[i].Value = value
+				return
+			}
+		}
+		*target = append(*target, UnknownField{
+			Name:  name,
+			Value: value,
+		})
+	}, cont)
+}
@@ -0,0 +1,127 @@
+package sb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnknownFields(t *testing.T) {
+	type V2 struct {
+		Foo   int
+		Qux   map[string][]int
+		Bar   string
+		Extra []any
+	}
+	type V1 struct {
+		Foo     int
+		Bar     string
+		Unknown UnknownFields
+	}
+
+	v2 := V2{
+		Foo: 1,
+		Qux: map[string][]int{
+			"a": {1, 2},
+		},
+		Bar:   "bar",
+		Extra: []any{"foo", 42},
+	}
+
+	var v1 V1
+	if err := Copy(Marshal(v2), Unmarshal(&v1)); err != nil {
+		t.Fatal(err)
+	}
+	if v1.Foo != 1 || v1.Bar != "bar" || len(v1.Unknown) != 2 ||
+		v1.Unknown[0].Name != "Qux" || v1.Unknown[1].Name != "Extra" {
+		t.Fatalf("got %+v", v1)
+	}
+
+	// strict
+	var strict V1
+	if err := Copy(
+		Marshal(v2),
+		UnmarshalValue(DefaultCtx.Strict(), reflect.ValueOf(&strict), nil),
+	); !is(err, UnknownFieldName) {
+		t.Fatalf("got %v", err)
+	}
+
+	// reset on each unmarshal
+	var reused V1
+	for i := 0; i < 3; i++ {
+		if err := Copy(Marshal(v2), Unmarshal(&reused)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(reused.Unknown) != 2 {
+		t.Fatalf("got %+v", reused.Unknown)
+	}
+	if err := Copy(
+		Marshal(struct {
+			Foo int
+			Qux int
+		}{1, 2}),
+		Unmarshal(&reused),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(reused.Unknown) != 1 || reused.Unknown[0].Name != "Qux" {
+		t.Fatalf("got %+v", reused.Unknown)
+	}
+
+	// replaced by name in patch mode
+	patched := V1{
+		Unknown: UnknownFields{
+			{Name: "Baz", Value: Tokens{{Kind: KindInt, Value: 1}}},
+			{Name: "Qux", Value: Tokens{{Kind: KindInt, Value: 1}}},
+		},
+	}
+	if err := Copy(
+		Marshal(v2),
+		UnmarshalValue(DefaultCtx.Patch(SliceReplace), reflect.ValueOf(&patched), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if len(patched.Unknown) != 3 ||
+		patched.Unknown[0].Name != "Baz" ||
+		patched.Unknown[1].Name != "Qux" || patched.Unknown[1].Value[0].Kind != KindMap {
+		t.Fatalf("got %+v", patched.Unknown)
+	}
+
+	// re-emit after known fields, sorted by name
+	v1.Foo = 2
+	tokens, err := TokensFromStream(Marshal(v1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[5].Value != "Extra" {
+		t.Fatalf("got %+v", tokens)
+	}
+	var v2b V2
+	if err := Copy(tokens.Iter(), Unmarshal(&v2b)); err != nil {
+		t.Fatal(err)
+	}
+	v2.Foo = 2
+	if !reflect.DeepEqual(v2, v2b) {
+		t.Fatalf("got %+v", v2b)
+	}
+
+	// deterministic
+	v1.Unknown[0], v1.Unknown[1] = v1.Unknown[1], v1.Unknown[0]
+	if MustCompare(Marshal(v1), tokens.Iter()) != 0 {
+		t.Fatal()
+	}
+
+	// incomplete value
+	var v1b V1
+	err = Copy(
+		Tokens{
+			{Kind: KindObject},
+			{Kind: KindString, Value: "Qux"},
+			{Kind: KindArray},
+		}.Iter(),
+		Unmarshal(&v1b),
+	)
+	if !is(err, UnmarshalError) {
+		t.Fatal()
+	}
+}
@@ -16,6 +16,8 @@ type SBUnmarshaler interface {
 	UnmarshalSB(ctx Ctx, cont Sink) Sink
 }
 
+var sbUnmarshalerType = reflect.TypeOf((*SBUnmarshaler)(nil)).Elem()
+
 func Unmarshal(target any) Sink {
 	return UnmarshalValue(
 		Ctx{
@@ -52,14 +54,40 @@ func UnmarshalValue(ctx Ctx, target reflect.Value, cont Sink) Sink {
 	if ctx.Unmarshal == nil {
 		ctx.Unmarshal = UnmarshalValue
 	}
+	if ctx.SharedPointers && ctx.pointers == nil {
+		ctx.pointers = newPointerTable()
+	}
+	if ctx.opts().AtomicUnmarshal && target.Kind() == reflect.Ptr && !target.IsNil() {
+		return unmarshalAtomic(ctx, target, cont)
+	}
+	if ctx.opts().Merge == MergeReplace && target.Kind() == reflect.Ptr && !target.IsNil() {
+		return unmarshalReplace(ctx, target, cont)
+	}
+	if ctx.skipValidate {
+		ctx.skipValidate = false
+	} else if target.Kind() == reflect.Ptr && !target.IsNil() {
+		info := getValidatorInfo(target.Type())
+		if info.IsValidator {
+			return unmarshalValidator(ctx, target, cont)
+		} else if info.HasValidators && ctx.opts().invalidValues == nil {
+			return unmarshalValidated(ctx, target, cont)
+		}
+	}
 
 	return func(token *Token) (next Sink, err error) {
 		defer func() {
 			err = we.With(WithPath(ctx))(err)
 		}()
 
+		if target.Kind() == reflect.Ptr {
+			if codec, ok := ctx.opts().TypeCodecs.Get(target.Type().Elem()); ok && codec.Unmarshal != nil {
+				return codec.Unmarshal(ctx, target, cont)(token)
+			}
+		}
+
 		// convert literal token
-		if token.Valid() && token.Kind == KindLiteral {
+		if token.Valid() && token.Kind == KindLiteral &&
+			!(target.IsValid() && target.Type().Implements(tokenCapturerType)) {
 			copy := *token
 			token = &copy
 
@@ -158,6 +186,18 @@ func UnmarshalValue(ctx Ctx, target reflect.Value, cont Sink) Sink {
 
 		}
 
+		if ctx.pointers != nil && token.Valid() {
+			switch token.Kind {
+			case KindPointerID:
+				return unmarshalPointerID(ctx, target, token.Value.(int), cont)
+			case KindPointerRef:
+				if err := unmarshalPointerRef(ctx, target, token.Value.(int)); err != nil {
+					return nil, err
+				}
+				return cont, nil
+			}
+		}
+
 		if target.IsValid() {
 			switch v := target.Interface().(type) {
 
@@ -326,6 +366,10 @@ func UnmarshalValue(ctx Ctx, target reflect.Value, cont Sink) Sink {
 
 		hasConcreteType := false
 		if valueKind == reflect.Ptr {
+			if ctx.opts().Merge == MergePatch && !target.IsNil() && !target.Elem().IsNil() {
+				// patch existing value
+				return ctx.Unmarshal(ctx, target.Elem(), cont)(token)
+			}
 			// deref
 			t := reflect.New(valueType.Elem())
 			return ctx.Unmarshal(
@@ -344,6 +388,12 @@ func UnmarshalValue(ctx Ctx, target reflect.Value, cont Sink) Sink {
 			}
 		}
 
+		if !hasConcreteType {
+			if u, ok := registeredUnions.Load(valueType); ok {
+				return unmarshalUnion(ctx, u.(*union), target, token, cont)
+			}
+		}
+
 		switch token.Kind {
 
 		case KindBool:
@@ -725,6 +775,9 @@ func UnmarshalSlice(
 	cont Sink,
 ) Sink {
 	slice := target.Elem()
+	if ctx.opts().Merge == MergePatch && ctx.opts().SliceMerge == SliceReplace {
+		slice = reflect.Zero(valueType)
+	}
 	return ExpectKind(
 		ctx,
 		KindArray,
@@ -755,6 +808,9 @@ func unmarshalSlice(
 			target.Elem().Set(slice)
 			return cont, nil
 		}
+		if ctx.opts().MaxSliceLength > 0 && slice.Len() >= ctx.opts().MaxSliceLength {
+			return nil, we.With(WithPath(ctx), SliceTooLong)(UnmarshalError)
+		}
 		elemPtr := reflect.New(valueType.Elem())
 		slice = reflect.Append(slice, elemPtr.Elem())
 
@@ -802,6 +858,9 @@ func unmarshalGenericSlice(
 			target.Elem().Set(reflect.ValueOf(slice))
 			return cont, nil
 		}
+		if ctx.opts().MaxSliceLength > 0 && len(slice) >= ctx.opts().MaxSliceLength {
+			return nil, we.With(WithPath(ctx), SliceTooLong)(UnmarshalError)
+		}
 
 		var value any
 		return ctx.Unmarshal(
@@ -839,6 +898,17 @@ func unmarshalStruct(
 	valueType reflect.Type,
 	cont Sink,
 ) Sink {
+	fields := getStructFields(valueType)
+	var present []bool
+	if fields.TrackPresence && ctx.opts().Merge != MergePatch {
+		present = make([]bool, len(fields.Fields))
+	}
+	if fields.UnknownFields != nil && ctx.opts().Merge != MergePatch {
+		// captured fields of previous unmarshaling are stale
+		if v, err := target.Elem().FieldByIndexErr(fields.UnknownFields); err == nil {
+			v.SetZero()
+		}
+	}
 	var sink Sink
 	sink = func(p *Token) (Sink, error) {
 		if p == nil {
@@ -848,6 +918,11 @@ func unmarshalStruct(
 			)(UnmarshalError)
 		}
 		if p.Kind == KindObjectEnd {
+			if present != nil {
+				if err := checkFieldPresence(ctx, target, fields, present); err != nil {
+					return nil, err
+				}
+			}
 			return cont, nil
 		}
 		var name string
@@ -856,9 +931,7 @@ func unmarshalStruct(
 			ctx,
 			reflect.ValueOf(&name),
 			func(token *Token) (Sink, error) {
-				field, ok := valueType.FieldByNameFunc(func(str string) bool {
-					return str == name
-				})
+				i, ok := fields.ByName[name]
 				if !ok {
 					if ctx.DisallowUnknownStructFields {
 						// check field deprecation
@@ -876,6 +949,14 @@ func unmarshalStruct(
 							UnknownFieldName,
 							fmt.Errorf("field: %s", name),
 						)(UnmarshalError)
+					} else if fields.UnknownFields != nil && !fieldIsDeprecated(valueType, name) {
+						// capture
+						return unmarshalUnknownField(
+							ctx.WithPath(name),
+							target.Elem().FieldByIndex(fields.UnknownFields).Addr().Interface().(*UnknownFields),
+							name,
+							sink,
+						)(token)
 					} else {
 						// skip next value
 						var value any
@@ -887,9 +968,17 @@ func unmarshalStruct(
 					}
 
 				} else {
+					field := fields.Fields[i]
+					if present != nil {
+						present[i] = true
+					}
+					fieldValue, err := fieldByIndexAlloc(target.Elem(), field.Index)
+					if err != nil {
+						return nil, we.With(WithPath(ctx.WithPath(field.Name)), e5.With(UnmarshalError))(err)
+					}
 					return ctx.Unmarshal(
-						ctx.WithPath(target.Elem().Type().FieldByIndex(field.Index).Name),
-						target.Elem().FieldByIndex(field.Index).Addr(),
+						ctx.WithPath(field.Name),
+						fieldValue.Addr(),
 						sink,
 					)(token)
 				}
@@ -1013,6 +1102,7 @@ func unmarshalMap(
 	elemType reflect.Type,
 	cont Sink,
 ) Sink {
+	var n int
 	var sink Sink
 	sink = func(p *Token) (Sink, error) {
 		if p == nil {
@@ -1024,6 +1114,10 @@ func unmarshalMap(
 		if p.Kind == KindMapEnd {
 			return cont, nil
 		}
+		if ctx.opts().MaxMapLength > 0 && n >= ctx.opts().MaxMapLength {
+			return nil, we.With(WithPath(ctx), MapTooLarge)(UnmarshalError)
+		}
+		n++
 
 		key := reflect.New(keyType)
 		return ctx.Unmarshal(
@@ -1031,6 +1125,11 @@ func unmarshalMap(
 			key,
 			func(token *Token) (Sink, error) {
 				value := reflect.New(elemType)
+				if ctx.opts().Merge == MergePatch && !target.Elem().IsNil() {
+					if existing := target.Elem().MapIndex(key.Elem()); existing.IsValid() {
+						value.Elem().Set(cloneForUnmarshal(ctx, existing))
+					}
+				}
 
 				return ctx.Unmarshal(
 					ctx.WithPath(key.Elem().Interface()),
@@ -1087,6 +1186,9 @@ func unmarshalGenericMap(
 			target.Elem().Set(reflect.ValueOf(m))
 			return cont, nil
 		}
+		if ctx.opts().MaxMapLength > 0 && len(m) >= ctx.opts().MaxMapLength {
+			return nil, we.With(WithPath(ctx), MapTooLarge)(UnmarshalError)
+		}
 
 		var key any
 		return ctx.Unmarshal(
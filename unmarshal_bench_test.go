@@ -13,3 +13,71 @@ func BenchmarkUnmarshalInt(b *testing.B) {
 		}
 	}
 }
+
+type benchUnmarshalStruct struct {
+	I int
+	S string
+	F []float64
+	M map[string]int
+}
+
+var benchUnmarshalValue = benchUnmarshalStruct{
+	I: 42,
+	S: "foo",
+	F: []float64{1, 2, 3},
+	M: map[string]int{
+		"a": 1,
+		"b": 2,
+	},
+}
+
+func BenchmarkUnmarshalStruct(b *testing.B) {
+	tokens, err := TokensFromStream(Marshal(benchUnmarshalValue))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var v benchUnmarshalStruct
+		if err := Copy(
+			tokens.Iter(),
+			Unmarshal(&v),
+		); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalStructAtomic(b *testing.B) {
+	tokens, err := TokensFromStream(Marshal(benchUnmarshalValue))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var v benchUnmarshalStruct
+		if err := Copy(
+			tokens.Iter(),
+			UnmarshalAtomic(&v),
+		); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalStructAtomicNonEmpty(b *testing.B) {
+	tokens, err := TokensFromStream(Marshal(benchUnmarshalValue))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := benchUnmarshalValue
+		if err := Copy(
+			tokens.Iter(),
+			UnmarshalAtomic(&v),
+		); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
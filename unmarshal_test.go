@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -1881,6 +1882,33 @@ func TestUnmarshalFunc(t *testing.T) {
 	}
 }
 
+type testLiteralUnmarshaler int
+
+var _ SBUnmarshaler = new(testLiteralUnmarshaler)
+
+func (t *testLiteralUnmarshaler) UnmarshalSB(ctx Ctx, cont Sink) Sink {
+	return func(token *Token) (Sink, error) {
+		if token.Kind != KindInt {
+			return nil, we.With(WithPath(ctx), BadTokenKind)(UnmarshalError)
+		}
+		*t = testLiteralUnmarshaler(token.Value.(int))
+		return cont, nil
+	}
+}
+
+func TestSBUnmarshalerLiteral(t *testing.T) {
+	var v testLiteralUnmarshaler
+	if err := Copy(
+		DecodeJson(strings.NewReader(`42`), nil),
+		Unmarshal(&v),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if v != 42 {
+		t.Fatal()
+	}
+}
+
 type testFieldDeprecation1 struct {
 }
 
@@ -1926,3 +1954,58 @@ func TestFieldDeprecation(t *testing.T) {
 	}
 
 }
+
+func TestUnmarshalLimitCollections(t *testing.T) {
+	ctx := DefaultCtx.LimitCollections(2, 1)
+
+	var ints []int
+	if err := Copy(
+		Marshal([]int{1, 2}),
+		UnmarshalValue(ctx, reflect.ValueOf(&ints), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	err := Copy(
+		Marshal([]int{1, 2, 3}),
+		UnmarshalValue(ctx, reflect.ValueOf(&ints), nil),
+	)
+	if !is(err, UnmarshalError) || !is(err, SliceTooLong) {
+		t.Fatal()
+	}
+
+	var v any
+	err = Copy(
+		Marshal([]int{1, 2, 3}),
+		UnmarshalValue(ctx, reflect.ValueOf(&v), nil),
+	)
+	if !is(err, SliceTooLong) {
+		t.Fatal()
+	}
+
+	var m map[int]int
+	if err := Copy(
+		Marshal(map[int]int{1: 1}),
+		UnmarshalValue(ctx, reflect.ValueOf(&m), nil),
+	); err != nil {
+		t.Fatal(err)
+	}
+	err = Copy(
+		Marshal(map[string]map[int]int{"foo": {1: 1, 2: 2}}),
+		UnmarshalValue(ctx, reflect.ValueOf(&v), nil),
+	)
+	if !is(err, MapTooLarge) {
+		t.Fatal()
+	}
+	var path Path
+	if !as(err, &path) || path.String() != "/foo" {
+		t.Fatalf("got %v", path)
+	}
+	var m2 map[string]map[int]int
+	err = Copy(
+		Marshal(map[string]map[int]int{"foo": {1: 1, 2: 2}}),
+		UnmarshalValue(ctx, reflect.ValueOf(&m2), nil),
+	)
+	if !is(err, MapTooLarge) {
+		t.Fatal()
+	}
+}
@@ -0,0 +1,198 @@
+package sb
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/reusee/e5"
+)
+
+type TokenIndex int64
+
+var _ error = TokenIndex(0)
+
+func (t TokenIndex) Error() string {
+	return fmt.Sprintf("token index: %d", t)
+}
+
+func Validate(stream Stream) Stream {
+	v := new(validator)
+	var proc Proc
+	proc = func(token *Token) (Proc, error) {
+		if err := stream.Next(token); err != nil {
+			return nil, err
+		}
+		if err := v.check(token); err != nil {
+			return nil, err
+		}
+		if token.Invalid() {
+			return nil, nil
+		}
+		return proc, nil
+	}
+	return &proc
+}
+
+func ValidateSink(sink Sink) Sink {
+	v := new(validator)
+	var ret Sink
+	ret = func(token *Token) (Sink, error) {
+		if err := v.check(token); err != nil {
+			return nil, err
+		}
+		var err error
+		sink, err = sink(token)
+		if err != nil {
+			return nil, err
+		}
+		if sink == nil {
+			return nil, nil
+		}
+		return ret, nil
+	}
+	return ret
+}
+
+type validator struct {
+	tracker pathTracker
+	index   int64
+}
+
+func (v *validator) error(err error) error {
+	return we.With(
+		e5.With(TokenIndex(v.index)),
+		e5.With(v.tracker.Path()),
+		e5.With(err),
+	)(ValidateError)
+}
+
+func (v *validator) check(token *Token) error {
+	frames := v.tracker.frames
+	var top *pathFrame
+	if len(frames) > 0 {
+		top = &frames[len(frames)-1]
+	}
+
+	if token.Invalid() {
+		if top != nil {
+			return v.error(io.ErrUnexpectedEOF)
+		}
+		return nil
+	}
+
+	// string or bytes segments
+	if top != nil && (top.Kind == KindStringBegin || top.Kind == KindBytesBegin) {
+		segmentKind, endKind := KindString, KindStringEnd
+		if top.Kind == KindBytesBegin {
+			segmentKind, endKind = KindBytes, KindBytesEnd
+		}
+		switch token.Kind {
+		case segmentKind:
+			if err := checkTokenValue(token); err != nil {
+				return v.error(err)
+			}
+		case endKind:
+		default:
+			return v.error(BadSegment)
+		}
+		v.tracker.Push(token)
+		v.index++
+		return nil
+	}
+
+	switch token.Kind {
+
+	case KindStringEnd, KindBytesEnd:
+		return v.error(BadSegment)
+
+	case KindArrayEnd, KindObjectEnd, KindMapEnd, KindTupleEnd:
+		if top == nil {
+			return v.error(UnexpectedEndToken)
+		}
+		if top.Kind == KindTypeName || top.Kind == KindPointerID {
+			return v.error(DanglingPrefix)
+		}
+		if endKindOf(top.Kind) != token.Kind {
+			return v.error(UnexpectedEndToken)
+		}
+		if (top.Kind == KindObject || top.Kind == KindMap) && top.N%2 == 1 {
+			return v.error(OddMapTokens)
+		}
+
+	default:
+		if top != nil && top.Kind == KindObject && top.N%2 == 0 &&
+			token.Kind != KindString && token.Kind != KindStringBegin {
+			return v.error(BadObjectKey)
+		}
+		if err := checkTokenValue(token); err != nil {
+			return v.error(err)
+		}
+
+	}
+
+	v.tracker.Push(token)
+	v.index++
+	return nil
+}
+
+func endKindOf(kind Kind) Kind {
+	switch kind {
+	case KindArray:
+		return KindArrayEnd
+	case KindObject:
+		return KindObjectEnd
+	case KindMap:
+		return KindMapEnd
+	case KindTuple:
+		return KindTupleEnd
+	}
+	return KindInvalid
+}
+
+func checkTokenValue(token *Token) error {
+	var ok bool
+	switch token.Kind {
+	case KindMin, KindMax, KindNil, KindNaN,
+		KindArray, KindObject, KindMap, KindTuple,
+		KindStringBegin, KindBytesBegin:
+		ok = token.Value == nil
+	case KindBool:
+		_, ok = token.Value.(bool)
+	case KindString, KindTypeName, KindLiteral:
+		_, ok = token.Value.(string)
+	case KindBytes, KindRef:
+		_, ok = token.Value.([]byte)
+	case KindInt, KindPointerID, KindPointerRef:
+		_, ok = token.Value.(int)
+	case KindInt8:
+		_, ok = token.Value.(int8)
+	case KindInt16:
+		_, ok = token.Value.(int16)
+	case KindInt32:
+		_, ok = token.Value.(int32)
+	case KindInt64:
+		_, ok = token.Value.(int64)
+	case KindUint:
+		_, ok = token.Value.(uint)
+	case KindUint8:
+		_, ok = token.Value.(uint8)
+	case KindUint16:
+		_, ok = token.Value.(uint16)
+	case KindUint32:
+		_, ok = token.Value.(uint32)
+	case KindUint64:
+		_, ok = token.Value.(uint64)
+	case KindPointer:
+		_, ok = token.Value.(uintptr)
+	case KindFloat32:
+		_, ok = token.Value.(float32)
+	case KindFloat64:
+		_, ok = token.Value.(float64)
+	default:
+		return we.With(e5.With(token.Kind))(BadTokenKind)
+	}
+	if !ok {
+		return we.With(e5.Info("%s: %T", token.Kind, token.Value))(BadTokenValue)
+	}
+	return nil
+}
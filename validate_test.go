@@ -0,0 +1,156 @@
+package sb
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	type S struct {
+		Foo int
+		Bar []string
+		Baz map[int]any
+	}
+	values := []any{
+		42,
+		"foo",
+		[]int{1, 2, 3},
+		S{
+			Foo: 1,
+			Bar: []string{"a", "b"},
+			Baz: map[int]any{1: []byte("foo"), 2: nil, 3: 4.2},
+		},
+		Tuple{1, "2", 3.0},
+		Min,
+		Max,
+		NaN,
+	}
+	for _, value := range values {
+		if err := Copy(
+			Validate(Marshal(value)),
+			ValidateSink(Discard),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		// segments
+		buf := new(bytes.Buffer)
+		if err := Copy(Marshal(value), Encode(buf)); err != nil {
+			t.Fatal(err)
+		}
+		if err := Copy(
+			Validate(DecodeForCompare(buf)),
+			Discard,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Copy(
+		Validate(DecodeJson(strings.NewReader(`{"foo": [1, 2.5, "bar", null, true]}`), nil)),
+		Discard,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// multiple values
+	if err := Copy(
+		Validate(ConcatStreams(Marshal(1), Marshal([]int{1}))),
+		Discard,
+	); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateBad(t *testing.T) {
+	cases := []struct {
+		tokens Tokens
+		err    error
+		index  TokenIndex
+		path   string
+	}{
+		{
+			Tokens{{Kind: KindArrayEnd}},
+			UnexpectedEndToken, 0, "",
+		},
+		{
+			Tokens{{Kind: KindArray}, {Kind: KindInt, Value: 1}, {Kind: KindMapEnd}},
+			UnexpectedEndToken, 2, "/1",
+		},
+		{
+			Tokens{{Kind: KindArray}, {Kind: KindInt, Value: 1}},
+			io.ErrUnexpectedEOF, 2, "/1",
+		},
+		{
+			Tokens{{Kind: KindMap}, {Kind: KindInt, Value: 1}, {Kind: KindMapEnd}},
+			OddMapTokens, 2, "/1",
+		},
+		{
+			Tokens{{Kind: KindObject}, {Kind: KindInt, Value: 1}},
+			BadObjectKey, 1, "",
+		},
+		{
+			Tokens{{Kind: KindArray}, {Kind: KindTypeName, Value: "foo"}, {Kind: KindArrayEnd}},
+			DanglingPrefix, 2, "/0",
+		},
+		{
+			Tokens{{Kind: KindTypeName, Value: "foo"}},
+			io.ErrUnexpectedEOF, 1, "",
+		},
+		{
+			Tokens{{Kind: KindStringEnd}},
+			BadSegment, 0, "",
+		},
+		{
+			Tokens{{Kind: KindStringBegin}, {Kind: KindBytes, Value: []byte("foo")}},
+			BadSegment, 1, "",
+		},
+		{
+			Tokens{{Kind: KindObject}, {Kind: KindString, Value: "foo"}, {Kind: KindInt, Value: int64(1)}},
+			BadTokenValue, 2, "/foo",
+		},
+		{
+			Tokens{{Kind: Kind(3)}},
+			BadTokenKind, 0, "",
+		},
+	}
+
+	for i, c := range cases {
+		for _, fn := range []func() error{
+			func() error {
+				return Copy(Validate(c.tokens.Iter()), Discard)
+			},
+			func() error {
+				return Copy(c.tokens.Iter(), ValidateSink(Discard))
+			},
+		} {
+			err := fn()
+			if !is(err, ValidateError) || !is(err, c.err) {
+				t.Fatalf("%d: got %v", i, err)
+			}
+			var index TokenIndex
+			if !as(err, &index) || index != c.index {
+				t.Fatalf("%d: got %v", i, index)
+			}
+			var path Path
+			if !as(err, &path) || path.String() != c.path {
+				t.Fatalf("%d: got %v", i, path)
+			}
+		}
+	}
+
+	// guard encoding
+	buf := new(bytes.Buffer)
+	err := Copy(
+		Tokens{{Kind: KindArray}, {Kind: KindObjectEnd}}.Iter(),
+		ValidateSink(Encode(buf)),
+	)
+	if !is(err, UnexpectedEndToken) {
+		t.Fatal()
+	}
+	if buf.Len() != 1 {
+		t.Fatal()
+	}
+}